@@ -0,0 +1,156 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+/***** Anti-aliased rendering *****/
+
+// Blends c over the pixel at (x, y), with c's alpha scaled by coverage in [0, 1]. Both c and
+// the image are premultiplied, so this is the usual "over" operator.
+func blendPixel(img *image.RGBA, x, y int, c color.RGBA, coverage float64) {
+	if coverage <= 0 || !(image.Point{x, y}.In(img.Rect)) {
+		return
+	}
+	coverage = min(coverage, 1)
+	keep := 1 - float64(c.A)/255*coverage
+	i := img.PixOffset(x, y)
+	p := img.Pix[i : i+4 : i+4]
+	p[0] = uint8(float64(c.R)*coverage + float64(p[0])*keep + 0.5)
+	p[1] = uint8(float64(c.G)*coverage + float64(p[1])*keep + 0.5)
+	p[2] = uint8(float64(c.B)*coverage + float64(p[2])*keep + 0.5)
+	p[3] = uint8(float64(c.A)*coverage + float64(p[3])*keep + 0.5)
+}
+
+// Scales a premultiplied color's opacity
+func fade(c color.RGBA, opacity float64) color.RGBA {
+	return color.RGBA{
+		uint8(float64(c.R)*opacity + 0.5), uint8(float64(c.G)*opacity + 0.5),
+		uint8(float64(c.B)*opacity + 0.5), uint8(float64(c.A)*opacity + 0.5),
+	}
+}
+
+// Distance from (px, py) to the segment from (x1, y1) to (x2, y2)
+func distToSegment(px, py, x1, y1, x2, y2 float64) float64 {
+	dx, dy := x2-x1, y2-y1
+	t := 0.0
+	if l2 := dx*dx + dy*dy; l2 > 0 {
+		t = min(max(((px-x1)*dx+(py-y1)*dy)/l2, 0), 1)
+	}
+	return math.Hypot(px-(x1+t*dx), py-(y1+t*dy))
+}
+
+// Draws a line of the given width with round caps. Each pixel is covered by how far its
+// center is inside the stroke, which for a 1 pixel line gives the same ramp as Wu's
+// algorithm.
+func drawLineAA(img *image.RGBA, x1, y1, x2, y2, width float64, c color.RGBA) {
+	lineCoverage(img.Rect, x1, y1, x2, y2, width, func(x, y int, coverage float64) {
+		blendPixel(img, x, y, c, coverage)
+	})
+}
+
+// Calls cover with the coverage of every pixel within bounds that a line of the given width
+// touches. Only a band of pixels around the line is visited: the loop walks the major axis
+// and covers the stroke's extent along the minor one.
+func lineCoverage(bounds image.Rectangle, x1, y1, x2, y2, width float64, cover func(x, y int, coverage float64)) {
+	half := width / 2
+	steep := math.Abs(y2-y1) > math.Abs(x2-x1)
+	// (a, b) are (major, minor) coordinates
+	a1, b1, a2, b2 := x1, y1, x2, y2
+	if steep {
+		a1, b1, a2, b2 = y1, x1, y2, x2
+	}
+	if a1 > a2 {
+		a1, b1, a2, b2 = a2, b2, a1, b1
+	}
+	slope := 0.0
+	if a2 > a1 {
+		slope = (b2 - b1) / (a2 - a1)
+	}
+	reach := (half + 1) * math.Sqrt(1+slope*slope)
+	// For pixels alongside the segment, the distance is the perpendicular one, which is much
+	// cheaper than distToSegment
+	da, db := a2-a1, b2-b1
+	l2 := da*da + db*db
+	invLen := 0.0
+	if l2 > 0 {
+		invLen = 1 / math.Sqrt(l2)
+	}
+
+	aMin, aMax := bounds.Min.X, bounds.Max.X-1
+	bMin, bMax := bounds.Min.Y, bounds.Max.Y-1
+	if steep {
+		aMin, aMax, bMin, bMax = bMin, bMax, aMin, aMax
+	}
+	for a := max(int(math.Floor(a1-half-1)), aMin); a <= min(int(math.Ceil(a2+half+1)), aMax); a++ {
+		center := b1 + slope*(min(max(float64(a), a1), a2)-a1)
+		for b := max(int(math.Floor(center-reach)), bMin); b <= min(int(math.Ceil(center+reach)), bMax); b++ {
+			pa, pb := float64(a)-a1, float64(b)-b1
+			var d float64
+			if t := pa*da + pb*db; l2 > 0 && t >= 0 && t <= l2 {
+				d = math.Abs(pa*db-pb*da) * invLen
+			} else {
+				d = distToSegment(float64(a), float64(b), a1, b1, a2, b2)
+			}
+			if half+0.5-d <= 0 {
+				continue
+			}
+			if steep {
+				cover(b, a, half+0.5-d)
+			} else {
+				cover(a, b, half+0.5-d)
+			}
+		}
+	}
+}
+
+// Draws a line through points. Where its segments meet, each pixel takes the most any one of
+// them covers it, so translucent lines don't darken at the joints.
+func drawPolylineAA(img *image.RGBA, points [][2]float64, width float64, c color.RGBA) {
+	coverage := make(map[image.Point]float64)
+	for k := 1; k < len(points); k++ {
+		p1, p2 := points[k-1], points[k]
+		lineCoverage(img.Rect, p1[0], p1[1], p2[0], p2[1], width, func(x, y int, cov float64) {
+			pt := image.Point{x, y}
+			coverage[pt] = max(coverage[pt], cov)
+		})
+	}
+	for pt, cov := range coverage {
+		blendPixel(img, pt.X, pt.Y, c, cov)
+	}
+}
+
+// Filled circle with a smooth edge
+func drawCircleAA(img *image.RGBA, x, y, r float64, c color.RGBA) {
+	for py := int(math.Floor(y - r - 1)); py <= int(math.Ceil(y+r+1)); py++ {
+		for px := int(math.Floor(x - r - 1)); px <= int(math.Ceil(x+r+1)); px++ {
+			blendPixel(img, px, py, c, r+0.5-math.Hypot(float64(px)-x, float64(py)-y))
+		}
+	}
+}
+
+// Line with an arrowhead touching a node of radius nodeR at (x2, y2), shaped like
+// drawDirectedLine's, with sides R long
+func drawDirectedLineAA(img *image.RGBA, x1, y1, x2, y2, width float64, c, arrowc color.RGBA, nodeR, R float64) {
+	drawLineAA(img, x1, y1, x2, y2, width, c)
+	drawArrowheadAA(img, x1, y1, x2, y2, width, arrowc, nodeR, R)
+}
+
+// Just the arrowhead of drawDirectedLineAA
+func drawArrowheadAA(img *image.RGBA, x1, y1, x2, y2, width float64, arrowc color.RGBA, nodeR, R float64) {
+	dx, dy := x2-x1, y2-y1
+	r := math.Hypot(dx, dy)
+	if r == 0 {
+		return
+	}
+	rx, ry := dx/r, dy/r
+	tipX, tipY := x2-nodeR*rx, y2-nodeR*ry
+	leftX := tipX + arrowLeftRotMatrix.xx*R*rx + arrowLeftRotMatrix.xy*R*ry
+	leftY := tipY + arrowLeftRotMatrix.yx*R*rx + arrowLeftRotMatrix.yy*R*ry
+	rightX := tipX + arrowRightRotMatrix.xx*R*rx + arrowRightRotMatrix.xy*R*ry
+	rightY := tipY + arrowRightRotMatrix.yx*R*rx + arrowRightRotMatrix.yy*R*ry
+	drawLineAA(img, tipX, tipY, leftX, leftY, width, arrowc)
+	drawLineAA(img, tipX, tipY, rightX, rightY, width, arrowc)
+}
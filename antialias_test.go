@@ -0,0 +1,65 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestBlendPixel(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.SetRGBA(0, 0, color.RGBA{255, 255, 255, 255})
+	blendPixel(img, 0, 0, color.RGBA{0, 0, 255, 255}, 0.5)
+	if got, want := img.RGBAAt(0, 0), (color.RGBA{128, 128, 255, 255}); got != want {
+		t.Errorf("half coverage over white = %v, want %v", got, want)
+	}
+	// over a transparent pixel, and a coverage above 1
+	blendPixel(img, 1, 0, fade(color.RGBA{0, 0, 255, 255}, 0.5), 2)
+	if got, want := img.RGBAAt(1, 0), (color.RGBA{0, 0, 128, 128}); got != want {
+		t.Errorf("half opacity over transparent = %v, want %v", got, want)
+	}
+	// off the image
+	blendPixel(img, 5, 5, color.RGBA{255, 0, 0, 255}, 1)
+}
+
+func TestDrawLineAA(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	drawLineAA(img, 2, 10, 17, 10, 2, color.RGBA{0, 0, 0, 255})
+	for _, c := range []struct {
+		x, y int
+		a    uint8
+	}{
+		{10, 10, 255}, {10, 9, 128}, {10, 11, 128}, {10, 12, 0},
+		// round caps
+		{1, 10, 128}, {0, 10, 0}, {18, 10, 128}, {19, 10, 0},
+	} {
+		if got := img.RGBAAt(c.x, c.y).A; got != c.a {
+			t.Errorf("alpha at (%d, %d) = %d, want %d", c.x, c.y, got, c.a)
+		}
+	}
+
+	// a steep line, drawn in either direction, covers the same pixels
+	a := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	b := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	drawLineAA(a, 3, 1, 8, 18, 1, color.RGBA{0, 0, 0, 255})
+	drawLineAA(b, 8, 18, 3, 1, 1, color.RGBA{0, 0, 0, 255})
+	for i := range a.Pix {
+		if a.Pix[i] != b.Pix[i] {
+			t.Fatalf("line differs when drawn backwards at byte %d", i)
+		}
+	}
+	if a.RGBAAt(3, 1).A != 255 || a.RGBAAt(8, 18).A != 255 {
+		t.Errorf("steep line doesn't cover its end points")
+	}
+}
+
+func TestDrawEdgesAAOnce(t *testing.T) {
+	// undirected edges are stored both ways; with blending, drawing both would show
+	graph := PosGraph{{X: 0, Y: 0, Edges: []int{1}}, {X: 1, Y: 0, Edges: []int{0}}}
+	opts := RenderOptions{Width: 100, Height: 20, Scale: 1, Antialias: true, EdgeWidth: 1, EdgeOpacity: 0.5}
+	img := image.NewRGBA(image.Rect(0, 0, 100, 20))
+	drawGraph(img, graph, false, opts)
+	if got := img.RGBAAt(50, 10).A; got != 128 {
+		t.Errorf("alpha in the middle of a half opaque edge = %d, want 128", got)
+	}
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+
+	"github.com/clarkep/ppa-final/internal/workpool"
+)
+
+/***** Force-directed edge bundling *****/
+
+// Settings for bundleEdges, with distances in a drawing 1000 units across. The defaults follow
+// d3-ForceBundle [2], but with a bigger step, which bundles sparse graphs more visibly.
+type BundleOptions struct {
+	// Subdivision cycles; each one doubles the number of points on every edge
+	Cycles int
+	// Iterations in the first cycle; each later cycle runs 2/3 as many
+	Iterations int
+	// How far points move per unit of force in the first cycle; halved every cycle
+	Step float64
+	// Spring constant holding the points of an edge together
+	Stiffness float64
+	// Edges only attract each other if their compatibility, from 0 to 1, is at least this
+	Compatibility float64
+	// Size of the worker pool the edges are handed out to
+	Workers int
+}
+
+var defaultBundleOptions = BundleOptions{Cycles: 6, Iterations: 90, Step: 0.5, Stiffness: 0.1, Compatibility: 0.6,
+	Workers: runtime.GOMAXPROCS(0)}
+
+// Workers take edges in chunks of this many
+const bundleChunkSize = 64
+
+// Size of the drawing the bundling forces are computed in
+const bundleSpan = 1000
+
+// Graphs with more edges than this aren't bundled: every edge moves every point in every
+// iteration, which takes too long for bigger graphs even with few compatible pairs
+const bundleMaxEdges = 100000
+
+// Checks, before the layout is run, that a graph isn't too big to bundle
+func checkBundleSize(adj Adjacency, directed bool) error {
+	m := 0
+	for i := 0; i < adj.NumNodes(); i++ {
+		m += adj.Degree(i)
+	}
+	if !directed {
+		m /= 2
+	}
+	if m > bundleMaxEdges {
+		return fmt.Errorf("the graph has %d edges, and --bundle handles at most %d", m, bundleMaxEdges)
+	}
+	return nil
+}
+
+// An edge q compatible with another, and whether q runs the opposite way, so that its
+// subdivision points have to be matched up back to front
+type compatibleEdge struct {
+	q    int
+	flip bool
+}
+
+// How far Q is visible from P: the overlap of P with the projection of Q onto P's line
+func edgeVisibility(ps, pt, qs, qt Point) float64 {
+	p := pt.Sub(ps)
+	l2 := p.X*p.X + p.Y*p.Y
+	project := func(x Point) Point {
+		return ps.Add(p.Scale((x.Sub(ps).X*p.X + x.Sub(ps).Y*p.Y) / l2))
+	}
+	i0, i1 := project(qs), project(qt)
+	span := i1.Sub(i0).Norm()
+	if span == 0 {
+		return 0
+	}
+	midP := ps.Add(pt).Scale(0.5)
+	midI := i0.Add(i1).Scale(0.5)
+	return math.Max(0, 1-2*midP.Sub(midI).Norm()/span)
+}
+
+// Compatibility of edges P and Q from 0 to 1: the product of how parallel they are, how
+// similar their lengths are, how close together they are, and how much they overlap [1]
+func edgeCompatibility(ps, pt, qs, qt Point) float64 {
+	p, q := pt.Sub(ps), qt.Sub(qs)
+	lp, lq := p.Norm(), q.Norm()
+	if lp == 0 || lq == 0 {
+		return 0
+	}
+	angle := math.Abs(p.X*q.X+p.Y*q.Y) / (lp * lq)
+	lavg := (lp + lq) / 2
+	scale := 2 / (lavg/math.Min(lp, lq) + math.Max(lp, lq)/lavg)
+	position := lavg / (lavg + ps.Add(pt).Scale(0.5).Sub(qs.Add(qt).Scale(0.5)).Norm())
+	visibility := math.Min(edgeVisibility(ps, pt, qs, qt), edgeVisibility(qs, qt, ps, pt))
+	return angle * scale * position * visibility
+}
+
+// The edge u -> graph[u].Edges[j] from s to t, in bundling coordinates
+type bundledEdge struct {
+	u, j int
+	s, t Point
+}
+
+// Lists the edges each edge is at least threshold compatible with, in order. Compatibility is at
+// most its position term, lavg / (lavg + distance between the midpoints), and the scale term
+// keeps the lengths within a factor of 4/threshold - 3 of each other. So an edge of length l is
+// only compatible with edges whose midpoints are within l (2/threshold - 1) (1/threshold - 1),
+// and only those are looked up, in a grid of the midpoints, rather than every pair of edges.
+func findCompatible(edges []bundledEdge, threshold float64, pool *workpool.Pool) [][]compatibleEdge {
+	m := len(edges)
+	mids := make([]Point, m)
+	for e, edge := range edges {
+		mids[e] = edge.s.Add(edge.t).Scale(0.5)
+	}
+	// About one midpoint per cell, listed in CSR form, each cell in edge order
+	g := min(max(int(math.Sqrt(float64(m))), 1), 1024)
+	cellSize := float64(bundleSpan) / float64(g)
+	cell := func(x float64) int {
+		return min(max(int(x/cellSize), 0), g-1)
+	}
+	offsets := make([]int, g*g+1)
+	for _, p := range mids {
+		offsets[cell(p.Y)*g+cell(p.X)+1]++
+	}
+	for c := 0; c < g*g; c++ {
+		offsets[c+1] += offsets[c]
+	}
+	byCell := make([]int, m)
+	fill := append([]int(nil), offsets[:g*g]...)
+	for e, p := range mids {
+		c := cell(p.Y)*g + cell(p.X)
+		byCell[fill[c]] = e
+		fill[c]++
+	}
+
+	compatible := make([][]compatibleEdge, m)
+	pool.Run(m, bundleChunkSize, func(start, end int) {
+		for e := start; e < end; e++ {
+			ps, pt := edges[e].s, edges[e].t
+			// Midpoints are never 2 bundleSpans apart, so with a threshold of 0 every pair counts
+			radius := 2.0 * bundleSpan
+			if threshold > 0 {
+				radius = min(radius, pt.Sub(ps).Norm()*(2/threshold-1)*(1/threshold-1)*(1+1e-9)+1e-9)
+			}
+			mid := mids[e]
+			for cy := cell(mid.Y - radius); cy <= cell(mid.Y+radius); cy++ {
+				for cx := cell(mid.X - radius); cx <= cell(mid.X+radius); cx++ {
+					for _, q := range byCell[offsets[cy*g+cx]:offsets[cy*g+cx+1]] {
+						if q == e || mids[q].Sub(mid).Norm() > radius {
+							continue
+						}
+						qs, qt := edges[q].s, edges[q].t
+						if edgeCompatibility(ps, pt, qs, qt) >= threshold {
+							dp, dq := pt.Sub(ps), qt.Sub(qs)
+							compatible[e] = append(compatible[e], compatibleEdge{q, dp.X*dq.X+dp.Y*dq.Y < 0})
+						}
+					}
+				}
+			}
+			// Cells are visited in grid order; the forces are added up in edge order
+			sort.Slice(compatible[e], func(a, b int) bool { return compatible[e][a].q < compatible[e][b].q })
+		}
+	})
+	return compatible
+}
+
+// Resamples the polyline from s through points to t into n+1 equally long segments, and returns
+// the n points between them
+func subdividePath(s Point, points []Point, t Point, n int) []Point {
+	full := append(append([]Point{s}, points...), t)
+	total := 0.0
+	for k := 1; k < len(full); k++ {
+		total += full[k].Sub(full[k-1]).Norm()
+	}
+	out := make([]Point, 0, n)
+	seg := total / float64(n+1)
+	k, walked := 1, 0.0
+	for i := 1; i <= n; i++ {
+		target := seg * float64(i)
+		for k < len(full)-1 && walked+full[k].Sub(full[k-1]).Norm() < target {
+			walked += full[k].Sub(full[k-1]).Norm()
+			k++
+		}
+		a, b := full[k-1], full[k]
+		l := b.Sub(a).Norm()
+		f := 0.0
+		if l > 0 {
+			f = (target - walked) / l
+		}
+		out = append(out, a.Add(b.Sub(a).Scale(f)))
+	}
+	return out
+}
+
+// Bundles the edges of graph with force-directed edge bundling [1]: each edge becomes a
+// polyline whose points are pulled towards the matching points of compatible edges, so that
+// edges running the same way merge into bundles. The result goes in the nodes' Bends. Every
+// step computes new points from the old ones only, so chunks of edges run in parallel on a pool
+// of opts.Workers, and the result doesn't depend on how they are split.
+func bundleEdges(graph PosGraph, directed bool, opts BundleOptions) {
+	boundary := getBoundary(graph)
+	span := float64(max(boundary.Right-boundary.Left, boundary.Top-boundary.Bottom))
+	if span == 0 {
+		span = 1
+	}
+	toBundle := func(x, y float32) Point {
+		return Point{X: float64(x-boundary.Left) * bundleSpan / span, Y: float64(y-boundary.Bottom) * bundleSpan / span}
+	}
+	fromBundle := func(p Point) Point {
+		return Point{X: p.X*span/bundleSpan + float64(boundary.Left), Y: p.Y*span/bundleSpan + float64(boundary.Bottom)}
+	}
+
+	var edges []bundledEdge
+	forEachEdge(graph, directed, func(u, v, j int) {
+		e := bundledEdge{u, j, toBundle(graph[u].X, graph[u].Y), toBundle(graph[v].X, graph[v].Y)}
+		if e.s != e.t {
+			edges = append(edges, e)
+		}
+	})
+	m := len(edges)
+	pool := workpool.New(opts.Workers)
+	defer pool.Close()
+	compatible := findCompatible(edges, opts.Compatibility, pool)
+
+	points := make([][]Point, m)
+	next := make([][]Point, m)
+	for e := range edges {
+		points[e] = []Point{edges[e].s.Add(edges[e].t).Scale(0.5)}
+		next[e] = make([]Point, 1)
+	}
+	n, step, iterations := 1, opts.Step, float64(opts.Iterations)
+	for cycle := 0; cycle < opts.Cycles; cycle++ {
+		for it := 0; it < int(iterations); it++ {
+			pool.Run(m, bundleChunkSize, func(start, end int) {
+				for e := start; e < end; e++ {
+					s, t := edges[e].s, edges[e].t
+					kP := opts.Stiffness / (t.Sub(s).Norm() * float64(n+1))
+					for i, p := range points[e] {
+						prev, nxt := s, t
+						if i > 0 {
+							prev = points[e][i-1]
+						}
+						if i < n-1 {
+							nxt = points[e][i+1]
+						}
+						force := prev.Sub(p).Add(nxt.Sub(p)).Scale(kP)
+						for _, c := range compatible[e] {
+							qi := points[c.q][i]
+							if c.flip {
+								qi = points[c.q][n-1-i]
+							}
+							if d := qi.Sub(p); d.Norm() > 1e-6 {
+								force = force.Add(d.Scale(1 / d.Norm()))
+							}
+						}
+						next[e][i] = p.Add(force.Scale(step))
+					}
+				}
+			})
+			points, next = next, points
+		}
+		if cycle == opts.Cycles-1 {
+			break
+		}
+		n *= 2
+		step /= 2
+		iterations *= 2.0 / 3
+		pool.Run(m, bundleChunkSize, func(start, end int) {
+			for e := start; e < end; e++ {
+				points[e] = subdividePath(edges[e].s, points[e], edges[e].t, n)
+				next[e] = make([]Point, n)
+			}
+		})
+	}
+
+	for u := range graph {
+		graph[u].Bends = make([][]Point, graph[u].degree())
+	}
+	for e, edge := range edges {
+		bends := make([]Point, len(points[e]))
+		for i, p := range points[e] {
+			bends[i] = fromBundle(p)
+		}
+		graph[edge.u].Bends[edge.j] = bends
+	}
+	if !directed {
+		mirrorBends(graph)
+	}
+}
+
+// Gives the v -> u copy of every undirected edge the reverse of the u -> v copy's bends. The
+// k-th v in u's edge list is matched with the k-th u in v's.
+func mirrorBends(graph PosGraph) {
+	for u := range graph {
+		seen := make(map[int]int)
+		for j := 0; j < graph[u].degree(); j++ {
+			v := graph[u].neighbor(j)
+			k := seen[v]
+			seen[v]++
+			if v <= u || graph[u].Bends[j] == nil {
+				continue
+			}
+			for jj := 0; jj < graph[v].degree(); jj++ {
+				if graph[v].neighbor(jj) != u {
+					continue
+				}
+				if k > 0 {
+					k--
+					continue
+				}
+				bends := graph[u].Bends[j]
+				mirrored := make([]Point, len(bends))
+				for i, p := range bends {
+					mirrored[len(bends)-1-i] = p
+				}
+				graph[v].Bends[jj] = mirrored
+				break
+			}
+		}
+	}
+}
+
+/* Refs:
+   [1] D. Holten and J. J. van Wijk, "Force-Directed Edge Bundling for Graph Visualization",
+       Computer Graphics Forum 28(3), 2009
+   [2] https://github.com/upphiminn/d3.ForceBundle
+*/
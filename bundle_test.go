@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/clarkep/ppa-final/internal/workpool"
+)
+
+func TestEdgeCompatibility(t *testing.T) {
+	a, b := Point{X: 0, Y: 0}, Point{X: 10, Y: 0}
+	if got := edgeCompatibility(a, b, Point{X: 0, Y: 1}, Point{X: 10, Y: 1}); got < 0.9 {
+		t.Errorf("close parallel edges have compatibility %g", got)
+	}
+	if got := edgeCompatibility(a, b, Point{X: 10, Y: 1}, Point{X: 0, Y: 1}); got < 0.9 {
+		t.Errorf("close antiparallel edges have compatibility %g", got)
+	}
+	if got := edgeCompatibility(a, b, Point{X: 5, Y: -5}, Point{X: 5, Y: 5}); got != 0 {
+		t.Errorf("perpendicular edges have compatibility %g", got)
+	}
+	if got := edgeCompatibility(a, b, Point{X: 30, Y: 0}, Point{X: 40, Y: 0}); got != 0 {
+		t.Errorf("edges that don't overlap have compatibility %g", got)
+	}
+}
+
+// The grid finds the same compatible edges as comparing every pair
+func TestFindCompatible(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	edges := make([]bundledEdge, 400)
+	for e := range edges {
+		s := Point{X: rng.Float64() * bundleSpan, Y: rng.Float64() * bundleSpan}
+		// mostly short edges, and a few long ones
+		l := 20 + rng.ExpFloat64()*60
+		a := rng.Float64() * 2 * math.Pi
+		edges[e] = bundledEdge{s: s, t: s.Add(Point{X: math.Cos(a), Y: math.Sin(a)}.Scale(l))}
+	}
+	pool := workpool.New(3)
+	defer pool.Close()
+	for _, threshold := range []float64{0, 0.2, 0.6, 0.95} {
+		want := make([][]compatibleEdge, len(edges))
+		for e, p := range edges {
+			for q, o := range edges {
+				if q != e && edgeCompatibility(p.s, p.t, o.s, o.t) >= threshold {
+					dp, dq := p.t.Sub(p.s), o.t.Sub(o.s)
+					want[e] = append(want[e], compatibleEdge{q, dp.X*dq.X+dp.Y*dq.Y < 0})
+				}
+			}
+		}
+		if got := findCompatible(edges, threshold, pool); !reflect.DeepEqual(got, want) {
+			t.Errorf("threshold %g: grid finds different compatible edges", threshold)
+		}
+	}
+}
+
+func TestSubdividePath(t *testing.T) {
+	// an L of length 4, resampled into 4 segments of length 1
+	got := subdividePath(Point{X: 0, Y: 0}, []Point{{X: 2, Y: 0}}, Point{X: 2, Y: 2}, 3)
+	want := []Point{{X: 1, Y: 0}, {X: 2, Y: 0}, {X: 2, Y: 1}}
+	for i := range want {
+		if got[i].Sub(want[i]).Norm() > 1e-9 {
+			t.Fatalf("subdividePath = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestBundleEdges(t *testing.T) {
+	// Two long parallel edges close together, and a short one across
+	graph := PosGraph{
+		{X: 0, Y: 0, Edges: []int{1}},
+		{X: 100, Y: 0, Edges: []int{0}},
+		{X: 0, Y: 10, Edges: []int{3}},
+		{X: 100, Y: 10, Edges: []int{2}},
+		{X: 50, Y: 40, Edges: []int{5}},
+		{X: 50, Y: 60, Edges: []int{4}},
+	}
+	opts := defaultBundleOptions
+	opts.Cycles = 3
+	bundleEdges(graph, false, opts)
+
+	a, b := graph[0].Bends[0], graph[2].Bends[0]
+	if len(a) != 4 || len(b) != 4 {
+		t.Fatalf("got %d and %d bends, want 4 after 3 cycles", len(a), len(b))
+	}
+	// The middles of the parallel edges are pulled together
+	if gap := math.Abs(a[1].Y - b[1].Y); gap > 5 {
+		t.Errorf("parallel edges still %g apart in the middle", gap)
+	}
+	// Incompatible edges stay straight
+	for _, p := range graph[4].Bends[0] {
+		if math.Abs(p.X-50) > 1e-9 {
+			t.Errorf("lone edge bent to %v", p)
+		}
+	}
+	// The other copy of an undirected edge has the same bends backwards
+	back := graph[1].Bends[0]
+	for i := range a {
+		if a[i] != back[len(back)-1-i] {
+			t.Fatalf("reverse copy %v doesn't mirror %v", back, a)
+		}
+	}
+
+	// Bundled edges are drawn the same whatever the tile size
+	for _, antialias := range []bool{false, true} {
+		ropts := defaultRenderOptions
+		ropts.Width, ropts.Height, ropts.Antialias, ropts.EdgeOpacity = 211, 157, antialias, 0.5
+		serial := image.NewRGBA(image.Rect(0, 0, 211, 157))
+		drawGraphTiled(serial, graph, true, ropts, 1000)
+		tiled := image.NewRGBA(serial.Rect)
+		drawGraphTiled(tiled, graph, true, ropts, 16)
+		if !bytes.Equal(serial.Pix, tiled.Pix) {
+			t.Errorf("antialias=%v: bundled edges drawn differently in tiles", antialias)
+		}
+	}
+}
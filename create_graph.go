@@ -2,19 +2,140 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"compress/gzip"
 	"fmt"
+	"io"
+	"math"
 	"os"
+	"path/filepath"
 	"sort"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/clarkep/ppa-final/layout"
+	"github.com/klauspost/compress/zstd"
+)
+
+// The layout package's graph types, which the rest of this package uses unqualified
+type (
+	Graph     = layout.Graph
+	Adjacency = layout.Adjacency
+	Point     = layout.Point
 )
 
-// Graph type using adjacency list
-type Graph [][]int
+// A Graph together with its edge weights
+type WeightedGraph struct {
+	Graph
+	Weights EdgeWeights
+}
+
+func (g WeightedGraph) Weight(u, j int) float64 { return g.Weights.at(u, j) }
+
+// Per-edge weights, parallel to the adjacency lists of a Graph: weights[u][j] is the weight of
+// the edge from u to graph[u][j]. A nil EdgeWeights means every edge has weight 1.
+type EdgeWeights [][]float64
+
+// Weight of u's j'th edge
+func (w EdgeWeights) at(u, j int) float64 {
+	if w == nil {
+		return 1
+	}
+	return w[u][j]
+}
+
+// Attribute values keyed by attribute name
+type Attrs map[string]string
+
+// Node and edge attributes carried over from the input file. Nodes[i] holds the attributes
+// of node i, and Edges is keyed by (source, target) node index.
+type AttrTable struct {
+	Nodes []Attrs
+	Edges map[[2]int]Attrs
+}
+
+// A graph as read from an input file, along with whatever metadata the format carries
+type GraphData struct {
+	Graph   Graph
+	Weights EdgeWeights
+	// Set instead of Graph and Weights when the graph was loaded in CSR form
+	CSR *CSRGraph
+	// Layout coordinates given in the input file, nil if it has none
+	Coords []Point
+	// What the lenient edge list dialect skipped or merged; nil otherwise
+	Cleanup *EdgeListSummary
+	// Numeric node ids for CSR inputs, where building NodeIDs would cost too much memory. Nil
+	// if the ids are exactly 0..n-1.
+	NodeNums []int32
+	Directed bool
+	// Node ids as written in the file, indexed by node
+	NodeIDs []string
+	Attrs   AttrTable
+}
+
+// Guesses the input format from the file extension, falling back to a plain edge list.
+// Compression extensions are skipped, so "graph.dot.gz" is read as DOT.
+func formatFromFilename(filename string) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext == ".gz" || ext == ".zst" {
+		ext = strings.ToLower(filepath.Ext(strings.TrimSuffix(filename, filepath.Ext(filename))))
+	}
+	switch ext {
+	case ".graphml", ".xml":
+		return "graphml"
+	case ".dot", ".gv":
+		return "dot"
+	case ".mtx":
+		return "mtx"
+	case ".net", ".paj":
+		return "pajek"
+	case ".json":
+		return "json"
+	default:
+		return "edgelist"
+	}
+}
+
+// A node name, with its integer value parsed once up front for sorting
+type nodeKey struct {
+	name  string
+	num   int
+	isNum bool
+}
 
-// Converts graph from map to slice
-func convertGraph(graph map[int][]int) Graph {
-	keys := make([]int, len(graph))
+// Orders node names: integers numerically and before everything else, and the rest as plain
+// strings
+func (a nodeKey) less(b nodeKey) bool {
+	switch {
+	case a.isNum && b.isNum:
+		return a.num < b.num
+	case a.isNum != b.isNum:
+		return a.isNum
+	default:
+		return a.name < b.name
+	}
+}
+
+// Sorts node names in place, in the order they become node indices
+func sortNodeNames(names []string) {
+	sortKeys := make([]nodeKey, len(names))
+	for i, k := range names {
+		num, err := strconv.Atoi(k)
+		sortKeys[i] = nodeKey{k, num, err == nil}
+	}
+	sort.Slice(sortKeys, func(i, j int) bool { return sortKeys[i].less(sortKeys[j]) })
+	for i, k := range sortKeys {
+		names[i] = k.name
+	}
+}
+
+// Converts graph from map to slice. Also returns the node names, indexed by node. weights,
+// if not nil, is parallel to graph and is converted the same way.
+func convertGraph(graph map[string][]string, weights map[string][]float64) (Graph, EdgeWeights, []string) {
+	keys := make([]string, len(graph))
 	i := 0
 	for k, _ := range graph {
 		keys[i] = k
@@ -23,8 +144,8 @@ func convertGraph(graph map[int][]int) Graph {
 	// Put the keys in sorted order to make debugging easier: if the node names in the file
 	// start with 0 and don't have gaps, they will be the same as the node indices in the graph.
 	// Can put this behind a debug mode flag if we want.
-	sort.Ints(keys)
-	keysToIndices := make(map[int]int)
+	sortNodeNames(keys)
+	keysToIndices := make(map[string]int)
 	for i, k := range keys {
 		keysToIndices[k] = i
 	}
@@ -34,46 +155,264 @@ func convertGraph(graph map[int][]int) Graph {
 			out[i] = append(out[i], keysToIndices[neighbor_key])
 		}
 	}
-	return out
+	var outWeights EdgeWeights
+	if weights != nil {
+		outWeights = make(EdgeWeights, len(graph))
+		for i, k := range keys {
+			outWeights[i] = weights[k]
+		}
+	}
+	return out, outWeights, keys
+}
+
+// Input stream that also closes whatever it was layered on
+type inputReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (r *inputReader) Close() error {
+	var err error
+	for i := len(r.closers) - 1; i >= 0; i-- {
+		if e := r.closers[i].Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Opens an input file, or stdin if filename is "-". gzip and zstd compressed input is
+// recognized by its magic number, whatever the file is called, and decompressed on the fly.
+func openInput(filename string) (io.ReadCloser, error) {
+	in := &inputReader{}
+	var file *os.File
+	if filename == "-" {
+		file = os.Stdin
+	} else {
+		var err error
+		file, err = os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		in.closers = append(in.closers, file)
+	}
+
+	buffered := bufio.NewReaderSize(file, 1<<16)
+	magic, _ := buffered.Peek(4)
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			in.Close()
+			return nil, fmt.Errorf("%s: %v", filename, err)
+		}
+		in.Reader = gz
+		in.closers = append(in.closers, gz)
+	case bytes.HasPrefix(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		zr, err := zstd.NewReader(buffered)
+		if err != nil {
+			in.Close()
+			return nil, fmt.Errorf("%s: %v", filename, err)
+		}
+		in.Reader = zr
+		in.closers = append(in.closers, zr.IOReadCloser())
+	default:
+		in.Reader = buffered
+	}
+	return in, nil
 }
 
-// Builds graph from file input
-func buildGraphFromFile(filename string, directed bool) (Graph, error) {
-	file, err := os.Open(filename)
+// Builds graph from file input, or stdin if filename is "-". format selects the parser; if
+// empty, it is guessed from the file extension. directed only applies to formats that don't
+// say so themselves.
+func buildGraphFromFile(filename, format string, directed bool, opts EdgeListOptions) (*GraphData, error) {
+	if format == "" {
+		format = formatFromFilename(filename)
+	}
+	file, err := openInput(filename)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
 
-	graph := make(map[int][]int)
+	var data *GraphData
+	switch format {
+	case "edgelist":
+		src, err := io.ReadAll(file)
+		if err != nil {
+			return nil, err
+		}
+		return readEdgeListPar(src, directed, runtime.GOMAXPROCS(0), opts)
+	case "graphml":
+		data, err = readGraphML(file)
+	case "dot":
+		data, err = readDOT(file)
+	case "mtx":
+		data, err = readMatrixMarket(file)
+	case "pajek":
+		data, err = readPajek(file)
+	case "json":
+		data, err = readNodeLinkJSON(file, directed)
+	default:
+		return nil, fmt.Errorf("unknown input format '%s'", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := data.weightsFromAttrs(); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		parts := strings.Fields(line)
-		if len(parts) != 2 {
-			return nil, fmt.Errorf("invalid line format: %s", line)
+// Parses one edge list line: two node names and an optional non-negative weight
+func parseEdgeLine(line string) (u, v string, w float64, hasWeight bool, err error) {
+	parts := strings.Fields(line)
+	if len(parts) != 2 && len(parts) != 3 {
+		return "", "", 0, false, fmt.Errorf("invalid line format: %s", line)
+	}
+	w = 1.0
+	if len(parts) == 3 {
+		w, err = strconv.ParseFloat(parts[2], 64)
+		if err != nil || w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+			return "", "", 0, false, fmt.Errorf("invalid weight %s in line: %s", parts[2], line)
 		}
+		hasWeight = true
+	}
+	return parts[0], parts[1], w, hasWeight, nil
+}
 
-		u, err := strconv.Atoi(parts[0])
-		if err != nil {
-			return nil, fmt.Errorf("invalid node %s: %v", parts[0], err)
+// Options for reading edge lists
+type EdgeListOptions struct {
+	// Accept the tolerant dialect: skip comments, blank lines and headers, split on commas,
+	// tabs or spaces, ignore extra columns, and merge duplicate edges
+	Lenient bool
+	// What the lenient dialect does with self-loops: "drop" or "keep"
+	SelfLoops string
+}
+
+// What the lenient dialect skipped or merged while reading an edge list
+type EdgeListSummary struct {
+	Comments, Blank, Headers, ExtraColumns, SelfLoops, Duplicates int
+}
+
+func (s *EdgeListSummary) add(o EdgeListSummary) {
+	s.Comments += o.Comments
+	s.Blank += o.Blank
+	s.Headers += o.Headers
+	s.ExtraColumns += o.ExtraColumns
+	s.SelfLoops += o.SelfLoops
+	s.Duplicates += o.Duplicates
+}
+
+func (s *EdgeListSummary) Print() {
+	fmt.Printf("Skipped %d comment, %d blank and %d header lines\n", s.Comments, s.Blank, s.Headers)
+	fmt.Printf("Ignored extra columns on %d lines\n", s.ExtraColumns)
+	fmt.Printf("Dropped %d self-loops, merged %d duplicate edges\n", s.SelfLoops, s.Duplicates)
+}
+
+// Column names that mark the first line of an edge list as a header
+var edgeListHeaderWords = map[string]bool{
+	"source": true, "target": true, "from": true, "to": true, "src": true, "dst": true,
+	"node1": true, "node2": true, "fromnodeid": true, "tonodeid": true,
+}
+
+func isEdgeListComment(trimmed string) bool {
+	return trimmed[0] == '#' || trimmed[0] == '%'
+}
+
+func splitLenientEdgeLine(trimmed string) []string {
+	return strings.FieldsFunc(trimmed, func(c rune) bool {
+		return c == ',' || c == ' ' || c == '\t' || c == '\r'
+	})
+}
+
+// Finds a header in a lenient edge list: it can only be the first line that isn't blank or a
+// comment. Returns the header's byte offset, or -1 if there isn't one.
+func findEdgeListHeader(src []byte) int {
+	for start := 0; start < len(src); {
+		end := len(src)
+		if nl := bytes.IndexByte(src[start:], '\n'); nl >= 0 {
+			end = start + nl
+		}
+		trimmed := strings.TrimSpace(string(src[start:end]))
+		if trimmed != "" && !isEdgeListComment(trimmed) {
+			parts := splitLenientEdgeLine(trimmed)
+			if len(parts) >= 2 && edgeListHeaderWords[strings.ToLower(parts[0])] &&
+				edgeListHeaderWords[strings.ToLower(parts[1])] {
+				return start
+			}
+			return -1
+		}
+		start = end + 1
+	}
+	return -1
+}
+
+// Parses one line of the lenient edge list dialect. skip is set, and the line counted in
+// summary, for blank and comment lines.
+func parseLenientEdgeLine(line string, summary *EdgeListSummary) (u, v string, w float64, hasWeight, skip bool, err error) {
+	trimmed := strings.TrimSpace(line)
+	switch {
+	case trimmed == "":
+		summary.Blank++
+		return "", "", 0, false, true, nil
+	case isEdgeListComment(trimmed):
+		summary.Comments++
+		return "", "", 0, false, true, nil
+	}
+	parts := splitLenientEdgeLine(trimmed)
+	if len(parts) < 2 {
+		return "", "", 0, false, false, fmt.Errorf("invalid line format: %s", line)
+	}
+	w = 1.0
+	extra := len(parts) > 3
+	if len(parts) >= 3 {
+		x, err := strconv.ParseFloat(parts[2], 64)
+		if err == nil && x >= 0 && !math.IsNaN(x) && !math.IsInf(x, 0) {
+			w, hasWeight = x, true
+		} else {
+			extra = true
 		}
+	}
+	if extra {
+		summary.ExtraColumns++
+	}
+	return parts[0], parts[1], w, hasWeight, false, nil
+}
+
+// Reads a plain edge list, one "u v" pair of node names per line, optionally followed by a
+// non-negative edge weight. Names are arbitrary whitespace-free strings. Edges without a weight
+// get weight 1; if no line has one, the graph is unweighted.
+//
+// buildGraphFromFile reads edge lists with readEdgeListPar. This line-by-line version is kept as
+// the reference it is tested against, since it is simple enough to check by eye.
+func readEdgeList(r io.Reader, directed bool) (*GraphData, error) {
+	graph := make(map[string][]string)
+	weights := make(map[string][]float64)
+	weighted := false
 
-		v, err := strconv.Atoi(parts[1])
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		u, v, w, hasWeight, err := parseEdgeLine(scanner.Text())
 		if err != nil {
-			return nil, fmt.Errorf("invalid node %s: %v", parts[1], err)
+			return nil, fmt.Errorf("line %d: %v", lineNum, err)
 		}
+		weighted = weighted || hasWeight
 
 		// Add edges both ways for undirected graph
 		graph[u] = append(graph[u], v)
+		weights[u] = append(weights[u], w)
 		if !directed {
 			graph[v] = append(graph[v], u)
+			weights[v] = append(weights[v], w)
 		} else {
 			// Need to initialize nodes even if they have no outgoing edges
 			_, ok := graph[v]
 			if !ok {
-				graph[v] = make([]int, 0)
+				graph[v] = make([]string, 0)
 			}
 		}
 	}
@@ -82,14 +421,299 @@ func buildGraphFromFile(filename string, directed bool) (Graph, error) {
 		return nil, err
 	}
 
-	return convertGraph(graph), nil
+	if !weighted {
+		weights = nil
+	}
+	out, outWeights, names := convertGraph(graph, weights)
+	return &GraphData{Graph: out, Weights: outWeights, Directed: directed, NodeIDs: names}, nil
+}
+
+// Splits src into about n byte ranges, each ending just after a newline (or at the end of src)
+func splitAtNewlines(src []byte, n int) [][2]int {
+	var ranges [][2]int
+	chunkSize := (len(src) + n - 1) / max(n, 1)
+	start := 0
+	for start < len(src) {
+		end := min(start+chunkSize, len(src))
+		if nl := bytes.IndexByte(src[end-1:], '\n'); nl >= 0 {
+			end += nl
+		} else {
+			end = len(src)
+		}
+		ranges = append(ranges, [2]int{start, end})
+		start = end
+	}
+	return ranges
+}
+
+// Edges parsed from one chunk of an edge list file, in file order
+type edgeListChunk struct {
+	names    []string // (u, v) pairs
+	weights  []float64
+	weighted bool
+	unique   map[string]struct{}
+	lines    int
+	edges    []int // names translated to node indices
+	summary  EdgeListSummary
+	err      error
+}
+
+// Parses one chunk of an edge list. header is the chunk-relative offset of a header line to
+// skip, or -1.
+func parseEdgeListChunk(src []byte, opts EdgeListOptions, header int) edgeListChunk {
+	c := edgeListChunk{unique: make(map[string]struct{})}
+	offset := 0
+	for len(src) > 0 {
+		line := src
+		lineStart := offset
+		if nl := bytes.IndexByte(src, '\n'); nl >= 0 {
+			line, src = src[:nl], src[nl+1:]
+			offset += nl + 1
+		} else {
+			src = nil
+		}
+		c.lines++
+		if lineStart == header {
+			c.summary.Headers++
+			continue
+		}
+		var u, v string
+		var w float64
+		var hasWeight, skip bool
+		var err error
+		if opts.Lenient {
+			u, v, w, hasWeight, skip, err = parseLenientEdgeLine(string(line), &c.summary)
+		} else {
+			u, v, w, hasWeight, err = parseEdgeLine(strings.TrimSuffix(string(line), "\r"))
+		}
+		if err != nil {
+			c.err = err
+			return c
+		}
+		if skip {
+			continue
+		}
+		if opts.Lenient && u == v && opts.SelfLoops != "keep" {
+			// the node itself stays in the graph
+			c.summary.SelfLoops++
+			c.unique[u] = struct{}{}
+			continue
+		}
+		c.names = append(c.names, u, v)
+		c.weights = append(c.weights, w)
+		c.weighted = c.weighted || hasWeight
+		c.unique[u] = struct{}{}
+		c.unique[v] = struct{}{}
+	}
+	return c
+}
+
+// Parallel version of readEdgeList, giving exactly the same result for the strict dialect.
+// The input is split at line boundaries and the pieces are parsed by separate goroutines. The
+// node names they find are then merged and sorted, each piece's edges are translated to node
+// indices in parallel, and finally the adjacency lists are filled in file order.
+//
+// With opts.Lenient, duplicate edges are merged into the first one, adding up their weights,
+// and the returned GraphData carries a summary of everything that was skipped or merged.
+func readEdgeListPar(src []byte, directed bool, nWorkers int, opts EdgeListOptions) (*GraphData, error) {
+	header := -1
+	if opts.Lenient {
+		header = findEdgeListHeader(src)
+	}
+	ranges := splitAtNewlines(src, nWorkers)
+	chunks := make([]edgeListChunk, len(ranges))
+	var wg sync.WaitGroup
+	for i, r := range ranges {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			chunks[i] = parseEdgeListChunk(src[r[0]:r[1]], opts, header-r[0])
+		}()
+	}
+	wg.Wait()
+
+	// Report the first bad line in the file. Chunks before the failing one were parsed
+	// completely, so their line counts give the offset.
+	lineOffset := 0
+	weighted := false
+	var summary EdgeListSummary
+	for _, c := range chunks {
+		if c.err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineOffset+c.lines, c.err)
+		}
+		lineOffset += c.lines
+		weighted = weighted || c.weighted
+		summary.add(c.summary)
+	}
+
+	unique := make(map[string]struct{})
+	for _, c := range chunks {
+		for name := range c.unique {
+			unique[name] = struct{}{}
+		}
+	}
+	names := make([]string, 0, len(unique))
+	for name := range unique {
+		names = append(names, name)
+	}
+	sortNodeNames(names)
+	namesToIndices := make(map[string]int, len(names))
+	for i, name := range names {
+		namesToIndices[name] = i
+	}
+
+	degree := make([]int32, len(names))
+	for i := range chunks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := &chunks[i]
+			c.edges = make([]int, len(c.names))
+			for j, name := range c.names {
+				c.edges[j] = namesToIndices[name]
+			}
+			for j := 0; j < len(c.edges); j += 2 {
+				atomic.AddInt32(&degree[c.edges[j]], 1)
+				if !directed {
+					atomic.AddInt32(&degree[c.edges[j+1]], 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	graph := make(Graph, len(names))
+	var weights EdgeWeights
+	if weighted {
+		weights = make(EdgeWeights, len(names))
+	}
+	for i, d := range degree {
+		if d > 0 {
+			graph[i] = make([]int, 0, d)
+			if weighted {
+				weights[i] = make([]float64, 0, d)
+			}
+		}
+	}
+	// Lenient mode: edge -> positions of its entries in graph[u] and graph[v]. Undirected
+	// edges are keyed with the smaller node first.
+	var seen map[[2]int][2]int
+	if opts.Lenient {
+		seen = make(map[[2]int][2]int)
+	}
+	for _, c := range chunks {
+		for j := 0; j < len(c.edges); j += 2 {
+			u, v, w := c.edges[j], c.edges[j+1], c.weights[j/2]
+			if seen != nil {
+				key := [2]int{u, v}
+				if !directed && v < u {
+					key = [2]int{v, u}
+				}
+				if pos, ok := seen[key]; ok {
+					summary.Duplicates++
+					if weighted {
+						weights[key[0]][pos[0]] += w
+						if !directed {
+							weights[key[1]][pos[1]] += w
+						}
+					}
+					continue
+				}
+				if key[0] == u {
+					seen[key] = [2]int{len(graph[u]), len(graph[v])}
+				} else {
+					seen[key] = [2]int{len(graph[v]), len(graph[u])}
+				}
+				if u == v {
+					// the second entry of an undirected self-loop lands right after the first
+					seen[key] = [2]int{len(graph[u]), len(graph[u]) + 1}
+				}
+			}
+			graph[u] = append(graph[u], v)
+			if weighted {
+				weights[u] = append(weights[u], w)
+			}
+			if !directed {
+				graph[v] = append(graph[v], u)
+				if weighted {
+					weights[v] = append(weights[v], w)
+				}
+			}
+		}
+	}
+
+	out := &GraphData{Graph: graph, Weights: weights, Directed: directed, NodeIDs: names}
+	if opts.Lenient {
+		out.Cleanup = &summary
+	}
+	return out, nil
 }
 
-// Prints adjacency list
-func (g Graph) Print() {
-	for i, u := range g {
-		for j, _ := range u {
-			fmt.Printf("%d -> %d\n", i, j)
+// Fills in d.Weights from a numeric "weight" edge attribute, for formats that carry weights
+// as attributes. Edges without one get weight 1; the graph stays unweighted if none has one.
+func (d *GraphData) weightsFromAttrs() error {
+	weighted := false
+	weights := make(EdgeWeights, len(d.Graph))
+	for u, edges := range d.Graph {
+		weights[u] = make([]float64, len(edges))
+		for j, v := range edges {
+			weights[u][j] = 1
+			s, ok := d.edgeAttrs(u, v)["weight"]
+			if !ok {
+				continue
+			}
+			w, err := strconv.ParseFloat(s, 64)
+			if err != nil || w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+				return fmt.Errorf("invalid weight %s on edge %s -> %s", s, d.nodeName(u), d.nodeName(v))
+			}
+			weights[u][j] = w
+			weighted = true
 		}
 	}
+	if weighted {
+		d.Weights = weights
+	}
+	return nil
+}
+
+// Attributes of the edge u -> v, looking the other way round too if the graph is undirected
+func (d *GraphData) edgeAttrs(u, v int) Attrs {
+	attrs, ok := d.Attrs.Edges[[2]int{u, v}]
+	if !ok && !d.Directed {
+		attrs = d.Attrs.Edges[[2]int{v, u}]
+	}
+	return attrs
+}
+
+// Name of node i as given in the input file, or its index if the file had no names
+func (d *GraphData) nodeName(i int) string {
+	if i < len(d.NodeIDs) {
+		return d.NodeIDs[i]
+	}
+	if i < len(d.NodeNums) {
+		return strconv.Itoa(int(d.NodeNums[i]))
+	}
+	return strconv.Itoa(i)
+}
+
+// Text to label node i with: its "label" attribute if it has one, and its name otherwise
+func (d *GraphData) nodeLabel(i int) string {
+	if i < len(d.Attrs.Nodes) {
+		if label, ok := d.Attrs.Nodes[i]["label"]; ok && label != "" {
+			return label
+		}
+	}
+	return d.nodeName(i)
+}
+
+// The graph in whichever representation it was loaded in, with its weights
+func (d *GraphData) adjacency() Adjacency {
+	switch {
+	case d.CSR != nil:
+		return d.CSR
+	case d.Weights != nil:
+		return WeightedGraph{d.Graph, d.Weights}
+	default:
+		return d.Graph
+	}
 }
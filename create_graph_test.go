@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestReadEdgeListNames(t *testing.T) {
+	data, err := readEdgeList(strings.NewReader("svc-auth 10\n2 svc-auth\n10 a1f3\n"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"2", "10", "a1f3", "svc-auth"}; !reflect.DeepEqual(data.NodeIDs, want) {
+		t.Errorf("node ids = %v, want %v", data.NodeIDs, want)
+	}
+	if want := (Graph{{3}, {2}, nil, {1}}); !reflect.DeepEqual(data.Graph, want) {
+		t.Errorf("graph = %v, want %v", data.Graph, want)
+	}
+	outGraph := augmentGraph(data, make([]Point, len(data.Graph)))
+	if outGraph[3].Name != "svc-auth" {
+		t.Errorf("PosGraph name = %q, want svc-auth", outGraph[3].Name)
+	}
+}
+
+func TestReadEdgeListWeights(t *testing.T) {
+	data, err := readEdgeList(strings.NewReader("0 1 2.5\n1 2\n"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (EdgeWeights{{2.5}, {2.5, 1}, {1}}); !reflect.DeepEqual(data.Weights, want) {
+		t.Errorf("weights = %v, want %v", data.Weights, want)
+	}
+	if _, err := readEdgeList(strings.NewReader("0 1 -3\n"), false); err == nil {
+		t.Errorf("expected an error for a negative weight")
+	}
+}
+
+func TestReadEdgeListCSR(t *testing.T) {
+	src := "3 7 2\n7 12\n12 3\n3 12 0.5\n"
+	for _, directed := range []bool{true, false} {
+		want, err := readEdgeList(strings.NewReader(src), directed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, nWorkers := range []int{1, 2, 4} {
+			got, err := readEdgeListCSR([]byte(src), directed, nWorkers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantCSR, err := newCSRGraph(want.Graph, want.Weights)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.CSR, wantCSR) {
+				t.Errorf("directed=%v workers=%d: csr = %+v, want %+v", directed, nWorkers, got.CSR, wantCSR)
+			}
+			for i := range want.NodeIDs {
+				if got.nodeName(i) != want.NodeIDs[i] {
+					t.Errorf("node %d name = %s, want %s", i, got.nodeName(i), want.NodeIDs[i])
+				}
+			}
+		}
+	}
+
+	// The PosGraph of CSR input shares the CSR's targets instead of copying them
+	data, err := readEdgeListCSR([]byte(src), false, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph := augmentGraph(data, make([]Point, data.CSR.NumNodes()))
+	if &graph[1].Targets[0] != &data.CSR.Targets[data.CSR.Offsets[1]] {
+		t.Errorf("PosGraph edges copied out of the CSR")
+	}
+	var got [][2]int
+	forEachEdge(graph, false, func(u, v, j int) { got = append(got, [2]int{u, v}) })
+	if want := [][2]int{{0, 1}, {0, 2}, {0, 2}, {1, 2}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("edges of CSR PosGraph = %v, want %v", got, want)
+	}
+	if _, err := readEdgeListCSR([]byte("a b\n"), true, 1); err == nil {
+		t.Errorf("expected an error for non-integer node names")
+	}
+}
+
+// readEdgeListPar gives the same graph as the reference readEdgeList, however many chunks the
+// file is split into
+func TestReadEdgeListPar(t *testing.T) {
+	var sb strings.Builder
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(&sb, "n%d %d\n", (i*7)%61, (i*13)%97)
+		if i%5 == 0 {
+			fmt.Fprintf(&sb, "%d\tn%d 1.5\r\n", i%31, i%17)
+		}
+	}
+	srcs := []string{
+		sb.String(),
+		// unweighted, no newline at the end
+		"a b\nb c\nc a\nd a",
+		"x y",
+	}
+	for _, src := range srcs {
+		for _, directed := range []bool{true, false} {
+			want, err := readEdgeList(strings.NewReader(src), directed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// nWorkers is also how many chunks the file is split into
+			for _, nWorkers := range []int{1, 2, 3, 8, 64, 1000} {
+				got, err := readEdgeListPar([]byte(src), directed, nWorkers, EdgeListOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("%.10q directed=%v workers=%d: parallel parse differs from sequential", src, directed, nWorkers)
+				}
+			}
+		}
+	}
+}
+
+func TestReadEdgeListParErrorLine(t *testing.T) {
+	lines := make([]string, 100)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("%d %d", i, i+1)
+	}
+	lines[72] = "72 73 x"
+	lines[90] = "90"
+	src := []byte(strings.Join(lines, "\n"))
+	for _, nWorkers := range []int{1, 4, 16} {
+		_, err := readEdgeListPar(src, true, nWorkers, EdgeListOptions{})
+		if err == nil || !strings.HasPrefix(err.Error(), "line 73:") {
+			t.Errorf("workers=%d: error = %v, want one for line 73", nWorkers, err)
+		}
+		_, err = readEdgeListCSR(src, true, nWorkers)
+		if err == nil || !strings.HasPrefix(err.Error(), "line 73:") {
+			t.Errorf("workers=%d: CSR error = %v, want one for line 73", nWorkers, err)
+		}
+	}
+}
+
+func TestOpenInputCompressed(t *testing.T) {
+	const src = "0 1\n1 2\n"
+	dir := t.TempDir()
+
+	var gzBuf bytes.Buffer
+	gz := gzip.NewWriter(&gzBuf)
+	gz.Write([]byte(src))
+	gz.Close()
+	zw, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		"plain.txt":    []byte(src),
+		"graph.gz":     gzBuf.Bytes(),
+		"graph.zst":    zw.EncodeAll([]byte(src), nil),
+		"misnamed.txt": gzBuf.Bytes(),
+	}
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, contents, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		data, err := buildGraphFromFile(path, "", false, EdgeListOptions{})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if want := (Graph{{1}, {0, 2}, {1}}); !reflect.DeepEqual(data.Graph, want) {
+			t.Errorf("%s: graph = %v, want %v", name, data.Graph, want)
+		}
+	}
+	if f := formatFromFilename("deps.dot.gz"); f != "dot" {
+		t.Errorf("format of deps.dot.gz = %s, want dot", f)
+	}
+}
+
+func TestReadEdgeListLenient(t *testing.T) {
+	src := "# Directed graph: example.txt\n" +
+		"# Nodes: 4 Edges: 6\n" +
+		"FromNodeId\tToNodeId\n" +
+		"\n" +
+		"a\tb\t2\textra\n" +
+		"b,c\n" +
+		"b a 3\n" +
+		"c c\n" +
+		"a b 1\n" +
+		"% matrix market style comment\n" +
+		"d c\n"
+	data, err := readEdgeListPar([]byte(src), false, 3, EdgeListOptions{Lenient: true, SelfLoops: "drop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "b", "c", "d"}; !reflect.DeepEqual(data.NodeIDs, want) {
+		t.Errorf("node ids = %v, want %v", data.NodeIDs, want)
+	}
+	if want := (Graph{{1}, {0, 2}, {1, 3}, {2}}); !reflect.DeepEqual(data.Graph, want) {
+		t.Errorf("graph = %v, want %v", data.Graph, want)
+	}
+	if want := (EdgeWeights{{6}, {6, 1}, {1, 1}, {1}}); !reflect.DeepEqual(data.Weights, want) {
+		t.Errorf("weights = %v, want %v", data.Weights, want)
+	}
+	want := EdgeListSummary{Comments: 3, Blank: 1, Headers: 1, ExtraColumns: 1, SelfLoops: 1, Duplicates: 2}
+	if *data.Cleanup != want {
+		t.Errorf("summary = %+v, want %+v", *data.Cleanup, want)
+	}
+
+	data, err = readEdgeListPar([]byte("x x\nx y\n"), true, 1, EdgeListOptions{Lenient: true, SelfLoops: "keep"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Graph{{0, 1}, nil}); !reflect.DeepEqual(data.Graph, want) {
+		t.Errorf("graph with self-loops kept = %v, want %v", data.Graph, want)
+	}
+
+	// Nodes named u and v are an edge, not a header
+	data, err = readEdgeListPar([]byte("u v\nv w\n"), true, 1, EdgeListOptions{Lenient: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.NodeIDs) != 3 || data.Cleanup.Headers != 0 {
+		t.Errorf("first edge u v taken for a header: nodes %v, summary %+v", data.NodeIDs, *data.Cleanup)
+	}
+}
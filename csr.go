@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// Compressed sparse row graph: node u's edges go to Targets[Offsets[u]:Offsets[u+1]], with
+// the matching Weights if the graph is weighted. Takes a fraction of the memory of a Graph,
+// which needs a slice header and a separate allocation per node.
+type CSRGraph struct {
+	Offsets []int32
+	Targets []int32
+	// Nil for unweighted graphs
+	Weights []float32
+}
+
+func (g *CSRGraph) NumNodes() int         { return len(g.Offsets) - 1 }
+func (g *CSRGraph) Degree(u int) int      { return int(g.Offsets[u+1] - g.Offsets[u]) }
+func (g *CSRGraph) Neighbor(u, j int) int { return int(g.Targets[int(g.Offsets[u])+j]) }
+
+func (g *CSRGraph) Weight(u, j int) float64 {
+	if g.Weights == nil {
+		return 1
+	}
+	return float64(g.Weights[int(g.Offsets[u])+j])
+}
+
+// Converts a Graph, and its weights if any, to CSR form
+func newCSRGraph(graph Graph, weights EdgeWeights) (*CSRGraph, error) {
+	m := 0
+	for _, u := range graph {
+		m += len(u)
+	}
+	if m > math.MaxInt32 || len(graph) > math.MaxInt32 {
+		return nil, fmt.Errorf("graph too large for CSR form: %d nodes, %d edges", len(graph), m)
+	}
+	out := &CSRGraph{
+		Offsets: make([]int32, len(graph)+1),
+		Targets: make([]int32, 0, m),
+	}
+	if weights != nil {
+		out.Weights = make([]float32, 0, m)
+	}
+	for i, u := range graph {
+		for j, v := range u {
+			out.Targets = append(out.Targets, int32(v))
+			if weights != nil {
+				out.Weights = append(out.Weights, float32(weights[i][j]))
+			}
+		}
+		out.Offsets[i+1] = int32(len(out.Targets))
+	}
+	return out, nil
+}
+
+// Switches d over to CSR form, dropping the Graph and EdgeWeights
+func (d *GraphData) toCSR() error {
+	csr, err := newCSRGraph(d.Graph, d.Weights)
+	if err != nil {
+		return err
+	}
+	d.CSR, d.Graph, d.Weights = csr, nil, nil
+	return nil
+}
+
+// Parses a non-negative integer node id that fits in an int32
+func parseNodeNum(field []byte) (int32, bool) {
+	if len(field) == 0 || len(field) > 10 {
+		return 0, false
+	}
+	var x int64
+	for _, c := range field {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		x = x*10 + int64(c-'0')
+	}
+	if x > math.MaxInt32 {
+		return 0, false
+	}
+	return int32(x), true
+}
+
+// Edges parsed from one chunk of an edge list file, in file order
+type csrChunk struct {
+	edges    []int32 // (u, v) pairs
+	weights  []float32
+	weighted bool
+	maxID    int32
+	lines    int
+	err      error
+}
+
+func parseCSRChunk(src []byte) csrChunk {
+	c := csrChunk{maxID: -1}
+	var fields [4][]byte
+	for len(src) > 0 {
+		line := src
+		if nl := bytes.IndexByte(src, '\n'); nl >= 0 {
+			line, src = src[:nl], src[nl+1:]
+		} else {
+			src = nil
+		}
+		c.lines++
+
+		// split into at most 4 whitespace-separated fields
+		nFields := 0
+		for i := 0; i < len(line) && nFields < 4; {
+			for i < len(line) && (line[i] == ' ' || line[i] == '\t' || line[i] == '\r') {
+				i++
+			}
+			start := i
+			for i < len(line) && line[i] != ' ' && line[i] != '\t' && line[i] != '\r' {
+				i++
+			}
+			if i > start {
+				fields[nFields] = line[start:i]
+				nFields++
+			}
+		}
+		if nFields != 2 && nFields != 3 {
+			c.err = fmt.Errorf("invalid line format: %s", bytes.TrimSuffix(line, []byte("\r")))
+			return c
+		}
+
+		u, ok := parseNodeNum(fields[0])
+		if !ok {
+			c.err = fmt.Errorf("invalid node %s: CSR input needs integer node names in [0, 2^31)", fields[0])
+			return c
+		}
+		v, ok := parseNodeNum(fields[1])
+		if !ok {
+			c.err = fmt.Errorf("invalid node %s: CSR input needs integer node names in [0, 2^31)", fields[1])
+			return c
+		}
+		w := 1.0
+		if nFields == 3 {
+			var err error
+			w, err = strconv.ParseFloat(string(fields[2]), 32)
+			if err != nil || w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+				c.err = fmt.Errorf("invalid weight %s in line: %s", fields[2], line)
+				return c
+			}
+			c.weighted = true
+		}
+		c.edges = append(c.edges, u, v)
+		c.weights = append(c.weights, float32(w))
+		c.maxID = max(c.maxID, u, v)
+	}
+	return c
+}
+
+// Reads an integer edge list (the same format as readEdgeList, but with node names restricted
+// to non-negative integers) straight into CSR form, without building a map or a slice per
+// node. Gives the same nodes, in the same order, and the same edges as readEdgeList.
+//
+// Pass 1 splits the input at line boundaries and parses the pieces in parallel into flat edge
+// buffers. Pass 2 counts degrees in parallel, compacts the node ids that actually appear into
+// dense indices, and then places every edge in its row in file order.
+func readEdgeListCSR(src []byte, directed bool, nWorkers int) (*GraphData, error) {
+	// Pass 1: parse
+	ranges := splitAtNewlines(src, nWorkers)
+	chunks := make([]csrChunk, len(ranges))
+	var wg sync.WaitGroup
+	for i, r := range ranges {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			chunks[i] = parseCSRChunk(src[r[0]:r[1]])
+		}()
+	}
+	wg.Wait()
+
+	maxID := int32(-1)
+	m := 0
+	weighted := false
+	lineOffset := 0
+	for _, c := range chunks {
+		if c.err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineOffset+c.lines, c.err)
+		}
+		lineOffset += c.lines
+		maxID = max(maxID, c.maxID)
+		m += len(c.weights)
+		weighted = weighted || c.weighted
+	}
+	if !directed {
+		m *= 2
+	}
+	if m > math.MaxInt32 {
+		return nil, fmt.Errorf("graph too large for CSR form: %d edges", m)
+	}
+	// Ids are used to index the degree arrays directly, so don't let a few huge ids blow up
+	// memory use.
+	idSpace := int(maxID) + 1
+	if idSpace > 2*m+(1<<20) {
+		return nil, fmt.Errorf("node ids up to %d are too sparse for CSR input with %d edges", maxID, m)
+	}
+
+	// Pass 2: count degrees by raw id, and mark which ids appear at all
+	deg := make([]int32, idSpace)
+	seen := make([]int32, idSpace)
+	for i := range chunks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			edges := chunks[i].edges
+			for e := 0; e < len(edges); e += 2 {
+				u, v := edges[e], edges[e+1]
+				atomic.AddInt32(&deg[u], 1)
+				atomic.StoreInt32(&seen[u], 1)
+				atomic.StoreInt32(&seen[v], 1)
+				if !directed {
+					atomic.AddInt32(&deg[v], 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Compact the ids in numeric order, reusing seen as the raw id -> index map
+	out := &GraphData{Directed: directed}
+	n := int32(0)
+	for id := range seen {
+		if seen[id] != 0 {
+			seen[id] = n
+			n++
+		} else {
+			seen[id] = -1
+		}
+	}
+	index := seen
+	if int(n) != idSpace {
+		out.NodeNums = make([]int32, 0, n)
+		for id, i := range index {
+			if i >= 0 {
+				out.NodeNums = append(out.NodeNums, int32(id))
+			}
+		}
+	}
+
+	csr := &CSRGraph{
+		Offsets: make([]int32, n+1),
+		Targets: make([]int32, m),
+	}
+	if weighted {
+		csr.Weights = make([]float32, m)
+	}
+	for id, i := range index {
+		if i >= 0 {
+			csr.Offsets[i+1] = csr.Offsets[i] + deg[id]
+		}
+	}
+
+	// Placing edges is a cheap sequential scan over the buffers, and keeps each row in file
+	// order. The degree array becomes the per-row cursor.
+	cursor := deg
+	copy(cursor, csr.Offsets[:n])
+	for _, c := range chunks {
+		for e := 0; e < len(c.edges); e += 2 {
+			u, v := index[c.edges[e]], index[c.edges[e+1]]
+			csr.Targets[cursor[u]] = v
+			if weighted {
+				csr.Weights[cursor[u]] = c.weights[e/2]
+			}
+			cursor[u]++
+			if !directed {
+				csr.Targets[cursor[v]] = u
+				if weighted {
+					csr.Weights[cursor[v]] = c.weights[e/2]
+				}
+				cursor[v]++
+			}
+		}
+	}
+
+	out.CSR = csr
+	return out, nil
+}
+
+// Like buildGraphFromFile, but returns the graph in CSR form. Strict edge lists are parsed
+// straight into CSR; other formats, and the lenient edge list dialect, are converted after they
+// are read.
+func buildCSRFromFile(filename, format string, directed bool, opts EdgeListOptions) (*GraphData, error) {
+	if format == "" {
+		format = formatFromFilename(filename)
+	}
+	if format == "edgelist" && !opts.Lenient {
+		file, err := openInput(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		src, err := io.ReadAll(file)
+		if err != nil {
+			return nil, err
+		}
+		return readEdgeListCSR(src, directed, runtime.GOMAXPROCS(0))
+	}
+	data, err := buildGraphFromFile(filename, format, directed, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := data.toCSR(); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,516 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+/***** Graphviz DOT input *****/
+
+type dotToken struct {
+	text   string
+	quoted bool // quoted and HTML strings are always ids, never keywords or operators
+	line   int
+}
+
+// Splits DOT source into ids, quoted strings, HTML strings, edge operators and punctuation,
+// dropping comments and preprocessor-style '#' lines.
+func dotTokenize(src string) ([]dotToken, error) {
+	var tokens []dotToken
+	line := 1
+	atLineStart := true
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			atLineStart = true
+			i++
+			continue
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+			continue
+		case c == '#' && atLineStart:
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			continue
+		}
+		atLineStart = false
+
+		switch {
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+		case strings.HasPrefix(src[i:], "->") || strings.HasPrefix(src[i:], "--"):
+			tokens = append(tokens, dotToken{text: src[i : i+2], line: line})
+			i += 2
+		case strings.ContainsRune("{}[];,=:", rune(c)):
+			tokens = append(tokens, dotToken{text: src[i : i+1], line: line})
+			i++
+		case c == '"':
+			var sb strings.Builder
+			start := line
+			i++
+			for ; i < len(src) && src[i] != '"'; i++ {
+				// Like Graphviz, only \" and line continuations are escapes here. Everything else,
+				// such as \n, \l or \N, is kept as is for whatever reads the label.
+				if src[i] == '\\' && i+1 < len(src) && src[i+1] == '"' {
+					i++
+				} else if src[i] == '\\' && i+1 < len(src) && src[i+1] == '\n' {
+					// line continuation
+					i++
+					line++
+					continue
+				} else if src[i] == '\n' {
+					line++
+				}
+				sb.WriteByte(src[i])
+			}
+			if i >= len(src) {
+				return nil, fmt.Errorf("line %d: unterminated string", start)
+			}
+			i++
+			tokens = append(tokens, dotToken{text: sb.String(), quoted: true, line: start})
+		case c == '<':
+			depth := 0
+			start, startLine := i, line
+			for ; i < len(src); i++ {
+				if src[i] == '<' {
+					depth++
+				} else if src[i] == '>' {
+					depth--
+					if depth == 0 {
+						break
+					}
+				} else if src[i] == '\n' {
+					line++
+				}
+			}
+			if i >= len(src) {
+				return nil, fmt.Errorf("line %d: unterminated HTML string", startLine)
+			}
+			i++
+			tokens = append(tokens, dotToken{text: src[start+1 : i-1], quoted: true, line: startLine})
+		default:
+			start := i
+			for i < len(src) {
+				r := rune(src[i])
+				if r >= 0x80 || unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' ||
+					(r == '-' && i == start) {
+					i++
+				} else {
+					break
+				}
+			}
+			if i == start {
+				return nil, fmt.Errorf("line %d: unexpected character '%c'", line, c)
+			}
+			tokens = append(tokens, dotToken{text: src[start:i], line: line})
+		}
+	}
+	return tokens, nil
+}
+
+type dotParser struct {
+	tokens []dotToken
+	pos    int
+	out    *GraphData
+	strict bool
+
+	idsToIndices map[string]int
+	seenEdges    map[[2]int]bool
+}
+
+// Default attributes set by "node [...]" and "edge [...]" statements, scoped to the
+// enclosing (sub)graph
+type dotScope struct {
+	nodeAttrs, edgeAttrs Attrs
+}
+
+func (s dotScope) child() dotScope {
+	c := dotScope{make(Attrs), make(Attrs)}
+	for k, v := range s.nodeAttrs {
+		c.nodeAttrs[k] = v
+	}
+	for k, v := range s.edgeAttrs {
+		c.edgeAttrs[k] = v
+	}
+	return c
+}
+
+func (p *dotParser) peek() *dotToken {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+// Whether the next token is the (unquoted) punctuation or keyword s. Keywords are
+// case-insensitive in DOT.
+func (p *dotParser) at(s string) bool {
+	t := p.peek()
+	return t != nil && !t.quoted && strings.EqualFold(t.text, s)
+}
+
+func (p *dotParser) errorf(format string, args ...any) error {
+	if t := p.peek(); t != nil {
+		return fmt.Errorf("invalid DOT, line %d: %s", t.line, fmt.Sprintf(format, args...))
+	}
+	return fmt.Errorf("invalid DOT, at end of file: %s", fmt.Sprintf(format, args...))
+}
+
+func (p *dotParser) expect(s string) error {
+	if !p.at(s) {
+		return p.errorf("expected '%s'", s)
+	}
+	p.pos++
+	return nil
+}
+
+func isDotPunct(t *dotToken) bool {
+	return !t.quoted && (strings.Contains("{}[];,=:", t.text) || t.text == "->" || t.text == "--")
+}
+
+func (p *dotParser) id() (string, error) {
+	t := p.peek()
+	if t == nil || isDotPunct(t) {
+		return "", p.errorf("expected an id")
+	}
+	p.pos++
+	return t.text, nil
+}
+
+// Looks up or creates the node with the given id. New nodes start with the scope's node
+// defaults; attrs, if any, are set on top.
+func (p *dotParser) node(id string, scope dotScope, attrs Attrs) int {
+	i, ok := p.idsToIndices[id]
+	if !ok {
+		i = len(p.out.Graph)
+		p.idsToIndices[id] = i
+		p.out.Graph = append(p.out.Graph, nil)
+		p.out.NodeIDs = append(p.out.NodeIDs, id)
+		nodeAttrs := make(Attrs)
+		for k, v := range scope.nodeAttrs {
+			nodeAttrs[k] = v
+		}
+		p.out.Attrs.Nodes = append(p.out.Attrs.Nodes, nodeAttrs)
+	}
+	for k, v := range attrs {
+		p.out.Attrs.Nodes[i][k] = v
+	}
+	return i
+}
+
+func (p *dotParser) edge(u, v int, attrs Attrs) {
+	if p.strict && p.seenEdges[[2]int{u, v}] {
+		return
+	}
+	p.seenEdges[[2]int{u, v}] = true
+	p.out.Graph[u] = append(p.out.Graph[u], v)
+	if !p.out.Directed {
+		p.seenEdges[[2]int{v, u}] = true
+		p.out.Graph[v] = append(p.out.Graph[v], u)
+	}
+	edgeAttrs := make(Attrs)
+	for k, val := range attrs {
+		edgeAttrs[k] = val
+	}
+	p.out.Attrs.Edges[[2]int{u, v}] = edgeAttrs
+}
+
+// Parses any number of "[a=b, c=d; ...]" lists, merging them into attrs
+func (p *dotParser) attrList(attrs Attrs) error {
+	for p.at("[") {
+		p.pos++
+		for !p.at("]") {
+			key, err := p.id()
+			if err != nil {
+				return err
+			}
+			if err := p.expect("="); err != nil {
+				return err
+			}
+			val, err := p.id()
+			if err != nil {
+				return err
+			}
+			attrs[key] = val
+			if p.at(",") || p.at(";") {
+				p.pos++
+			}
+		}
+		p.pos++
+	}
+	return nil
+}
+
+// Parses a node id with an optional port, which we don't use
+func (p *dotParser) nodeID() (string, error) {
+	id, err := p.id()
+	if err != nil {
+		return "", err
+	}
+	for i := 0; i < 2 && p.at(":"); i++ {
+		p.pos++
+		if _, err := p.id(); err != nil {
+			return "", err
+		}
+	}
+	return id, nil
+}
+
+// Parses one edge chain operand: a node id or a subgraph. Returns the nodes it stands for.
+func (p *dotParser) operand(scope dotScope) ([]int, error) {
+	if p.at("subgraph") || p.at("{") {
+		return p.subgraph(scope)
+	}
+	id, err := p.nodeID()
+	if err != nil {
+		return nil, err
+	}
+	return []int{p.node(id, scope, nil)}, nil
+}
+
+func (p *dotParser) subgraph(scope dotScope) ([]int, error) {
+	if p.at("subgraph") {
+		p.pos++
+		if !p.at("{") {
+			if _, err := p.id(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	return p.stmtList(scope.child())
+}
+
+// Parses statements up to and including the closing brace, returning every node mentioned
+func (p *dotParser) stmtList(scope dotScope) ([]int, error) {
+	var nodes []int
+	seen := make(map[int]bool)
+	mention := func(ns []int) {
+		for _, n := range ns {
+			if !seen[n] {
+				seen[n] = true
+				nodes = append(nodes, n)
+			}
+		}
+	}
+
+	for !p.at("}") {
+		if p.peek() == nil {
+			return nil, p.errorf("expected '}'")
+		}
+		switch {
+		case p.at(";"):
+			p.pos++
+			continue
+		case p.at("graph"):
+			p.pos++
+			if err := p.attrList(make(Attrs)); err != nil {
+				return nil, err
+			}
+			continue
+		case p.at("node"):
+			p.pos++
+			if err := p.attrList(scope.nodeAttrs); err != nil {
+				return nil, err
+			}
+			continue
+		case p.at("edge"):
+			p.pos++
+			if err := p.attrList(scope.edgeAttrs); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// graph attribute assignment: ID '=' ID
+		if p.pos+1 < len(p.tokens) && !p.tokens[p.pos+1].quoted && p.tokens[p.pos+1].text == "=" {
+			p.pos += 2
+			if _, err := p.id(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// node or edge statement
+		isSubgraph := p.at("subgraph") || p.at("{")
+		var id string
+		var first []int
+		var err error
+		if isSubgraph {
+			first, err = p.subgraph(scope)
+		} else {
+			id, err = p.nodeID()
+			if err == nil {
+				first = []int{p.node(id, scope, nil)}
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		mention(first)
+
+		if !p.at("->") && !p.at("--") {
+			if !isSubgraph {
+				attrs := make(Attrs)
+				if err := p.attrList(attrs); err != nil {
+					return nil, err
+				}
+				p.node(id, scope, attrs)
+			}
+			continue
+		}
+
+		chain := [][]int{first}
+		for p.at("->") || p.at("--") {
+			if p.out.Directed && p.at("--") {
+				return nil, p.errorf("'--' used in a digraph")
+			} else if !p.out.Directed && p.at("->") {
+				return nil, p.errorf("'->' used in an undirected graph")
+			}
+			p.pos++
+			next, err := p.operand(scope)
+			if err != nil {
+				return nil, err
+			}
+			mention(next)
+			chain = append(chain, next)
+		}
+		attrs := make(Attrs)
+		for k, v := range scope.edgeAttrs {
+			attrs[k] = v
+		}
+		if err := p.attrList(attrs); err != nil {
+			return nil, err
+		}
+		for i := 0; i+1 < len(chain); i++ {
+			for _, u := range chain[i] {
+				for _, v := range chain[i+1] {
+					p.edge(u, v, attrs)
+				}
+			}
+		}
+	}
+	p.pos++
+	return nodes, nil
+}
+
+// Reads the first graph of a DOT file. "digraph" makes the graph directed. Node ids are kept
+// in order of first mention, edge chains and subgraph operands expand to every edge they
+// describe, and node and edge attribute lists (including defaults) end up in the attribute
+// table. Graph attributes and ports are parsed but dropped.
+func readDOT(r io.Reader) (*GraphData, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := dotTokenize(string(src))
+	if err != nil {
+		return nil, fmt.Errorf("invalid DOT, %v", err)
+	}
+	p := &dotParser{
+		tokens:       tokens,
+		out:          &GraphData{Attrs: AttrTable{Edges: make(map[[2]int]Attrs)}},
+		idsToIndices: make(map[string]int),
+		seenEdges:    make(map[[2]int]bool),
+	}
+
+	if p.at("strict") {
+		p.strict = true
+		p.pos++
+	}
+	switch {
+	case p.at("digraph"):
+		p.out.Directed = true
+	case p.at("graph"):
+		p.out.Directed = false
+	default:
+		return nil, p.errorf("expected 'graph' or 'digraph'")
+	}
+	p.pos++
+	if !p.at("{") {
+		if _, err := p.id(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	if _, err := p.stmtList(dotScope{make(Attrs), make(Attrs)}); err != nil {
+		return nil, err
+	}
+	return p.out, nil
+}
+
+/***** Graphviz DOT output *****/
+
+// Quotes s as a DOT string. Only quotes are escaped, and newlines written as \n, so Graphviz
+// escapes like \N and \l pass through. A backslash at the end would escape the closing quote,
+// so it is followed by a line continuation, which readers drop.
+func dotQuote(s string) string {
+	s = strings.NewReplacer(`"`, `\"`, "\n", `\n`).Replace(s)
+	if strings.HasSuffix(s, `\`) {
+		s += "\\\n"
+	}
+	return `"` + s + `"`
+}
+
+// Formats an attribute list, with the names in sorted order
+func dotAttrList(attrs Attrs) string {
+	if len(attrs) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(attrs))
+	for _, k := range attrNames(attrs) {
+		parts = append(parts, dotQuote(k)+"="+dotQuote(attrs[k]))
+	}
+	return " [" + strings.Join(parts, ", ") + "]"
+}
+
+// Writes the laid out graph as DOT, with the attributes from the input file and each node's
+// position pinned with pos="x,y!", so neato -n (or neato, which honors the !) draws it as is.
+// Positions are in layout units; DOT's y axis points up like the layout's.
+func writeDOT(w io.Writer, graph PosGraph, data *GraphData, directed bool) error {
+	out := bufio.NewWriter(w)
+	kind, op := "graph", "--"
+	if directed {
+		kind, op = "digraph", "->"
+	}
+	ids := exportIDs(graph)
+	fmt.Fprintf(out, "%s G {\n", kind)
+	for i, node := range graph {
+		attrs := Attrs{}
+		if i < len(data.Attrs.Nodes) {
+			for k, v := range data.Attrs.Nodes[i] {
+				attrs[k] = v
+			}
+		}
+		if ids[i] != node.Name {
+			attrs["label"] = node.Name
+		}
+		attrs["pos"] = fmt.Sprintf("%g,%g!", node.X, node.Y)
+		fmt.Fprintf(out, "  %s%s;\n", dotQuote(ids[i]), dotAttrList(attrs))
+	}
+	forEachEdge(graph, directed, func(u, v, j int) {
+		fmt.Fprintf(out, "  %s %s %s%s;\n", dotQuote(ids[u]), op, dotQuote(ids[v]), dotAttrList(data.exportEdgeAttrs(u, v, j)))
+	})
+	fmt.Fprintf(out, "}\n")
+	return out.Flush()
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadDOT(t *testing.T) {
+	src := `
+// services
+digraph deps {
+	rankdir=LR;
+	node [shape=box];
+	"svc-auth" [label="Auth"];
+	a -> b -> c [weight=3];
+	a -> {b d}
+	subgraph cluster_x { edge [color=red]; d -> e }
+	/* trailing */
+}`
+	data, err := readDOT(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !data.Directed {
+		t.Errorf("expected digraph to be directed")
+	}
+	if want := []string{"svc-auth", "a", "b", "c", "d", "e"}; !reflect.DeepEqual(data.NodeIDs, want) {
+		t.Errorf("node ids = %v, want %v", data.NodeIDs, want)
+	}
+	if want := (Graph{nil, {2, 2, 4}, {3}, nil, {5}, nil}); !reflect.DeepEqual(data.Graph, want) {
+		t.Errorf("graph = %v, want %v", data.Graph, want)
+	}
+	if l := data.Attrs.Nodes[0]["label"]; l != "Auth" {
+		t.Errorf("label = %q, want Auth", l)
+	}
+	if s := data.Attrs.Nodes[3]["shape"]; s != "box" {
+		t.Errorf("node default shape = %q, want box", s)
+	}
+	if w := data.Attrs.Edges[[2]int{2, 3}]["weight"]; w != "3" {
+		t.Errorf("chained edge weight = %q, want 3", w)
+	}
+	if c := data.Attrs.Edges[[2]int{4, 5}]["color"]; c != "red" {
+		t.Errorf("subgraph edge color = %q, want red", c)
+	}
+}
+
+func TestReadDOTUndirected(t *testing.T) {
+	data, err := readDOT(strings.NewReader("strict graph { 1 -- 2 -- 1; 2 -- 3 }"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Directed {
+		t.Errorf("expected graph to be undirected")
+	}
+	if want := (Graph{{1}, {0, 2}, {1}}); !reflect.DeepEqual(data.Graph, want) {
+		t.Errorf("graph = %v, want %v", data.Graph, want)
+	}
+	if _, err := readDOT(strings.NewReader("graph { a -> b }")); err == nil {
+		t.Errorf("expected an error for '->' in an undirected graph")
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	src := `digraph { a [color="red"]; a -> b [weight=2]; b -> "say \"hi\""; }`
+	data, err := readDOT(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph := augmentGraph(data, []Point{{X: 1, Y: 2}, {X: 3.5, Y: 4}, {X: 5, Y: 6}})
+	var buf bytes.Buffer
+	if err := writeDOT(&buf, graph, data, true); err != nil {
+		t.Fatal(err)
+	}
+
+	back, err := readDOT(&buf)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, buf.String())
+	}
+	if !reflect.DeepEqual(back.NodeIDs, data.NodeIDs) || !reflect.DeepEqual(back.Graph, data.Graph) {
+		t.Errorf("round trip gave %v %v, want %v %v", back.NodeIDs, back.Graph, data.NodeIDs, data.Graph)
+	}
+	want := Attrs{"color": "red", "pos": "1,2!"}
+	if !reflect.DeepEqual(back.Attrs.Nodes[0], want) {
+		t.Errorf("attributes of a = %v, want %v", back.Attrs.Nodes[0], want)
+	}
+	if got := back.Attrs.Nodes[1]["pos"]; got != "3.5,4!" {
+		t.Errorf("pos of b = %s, want 3.5,4!", got)
+	}
+	if got := back.Attrs.Edges[[2]int{0, 1}]["weight"]; got != "2" {
+		t.Errorf("weight of a -> b = %s, want 2", got)
+	}
+}
+
+func TestWriteDOTEscapes(t *testing.T) {
+	// Graphviz escapes like \N and \l come back as written, not with their backslash doubled
+	names := []string{`a\`, `\N`, `left\l`, `say "\n"`, `x\"y`, "two\nlines"}
+	data := &GraphData{Graph: Graph{{1, 2}, nil, nil, nil, nil, nil}, NodeIDs: names, Directed: true,
+		Attrs: AttrTable{Nodes: []Attrs{{"label": `\N`}, {"label": `left\l`}, {}, {}, {}, {}}}}
+	graph := augmentGraph(data, make([]Point, len(names)))
+	var buf bytes.Buffer
+	if err := writeDOT(&buf, graph, data, true); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"\N" [`) || !strings.Contains(buf.String(), `"label"="left\l"`) {
+		t.Errorf("Graphviz escapes not written as is:\n%s", buf.String())
+	}
+	back, err := readDOT(&buf)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, buf.String())
+	}
+	// A newline is written as Graphviz's \n escape, and read back as one
+	want := append(names[:len(names)-1:len(names)-1], `two\nlines`)
+	if !reflect.DeepEqual(back.NodeIDs, want) || !reflect.DeepEqual(back.Graph, data.Graph) {
+		t.Errorf("round trip gave %q %v, want %q %v", back.NodeIDs, back.Graph, want, data.Graph)
+	}
+	if back.Attrs.Nodes[0]["label"] != `\N` || back.Attrs.Nodes[1]["label"] != `left\l` {
+		t.Errorf("labels read back as %q and %q", back.Attrs.Nodes[0]["label"], back.Attrs.Nodes[1]["label"])
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/***** Layout output files *****/
+
+// Settings for the output file writers
+type OutputOptions struct {
+	Render RenderOptions
+	PDF    PDFOptions
+	TikZ   TikZOptions
+}
+
+// Lengths per point for the units parseLength accepts
+var lengthUnits = map[string]float64{"pt": 1, "mm": 72 / 25.4, "cm": 72 / 2.54, "in": 72}
+
+// Parses a positive length like "12cm" or "400" into points. The unit is pt, mm, cm or in,
+// and defaults to pt.
+func parseLength(s string) (float64, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	unit := 1.0
+	for suffix, points := range lengthUnits {
+		if rest, ok := strings.CutSuffix(s, suffix); ok {
+			s, unit = rest, points
+			break
+		}
+	}
+	x, err := strconv.ParseFloat(s, 64)
+	if err != nil || !(x > 0) || math.IsInf(x, 0) {
+		return 0, fmt.Errorf("invalid length '%s'", s)
+	}
+	return x * unit, nil
+}
+
+// Output file extensions we can write
+var outputFormats = map[string]bool{
+	".png": true, ".json": true, ".svg": true, ".pdf": true, ".tex": true,
+	".dot": true, ".gv": true, ".graphml": true, ".gexf": true,
+}
+
+// Checks that path has an extension we can write, so a typo fails before the layout runs
+func checkOutputPath(path string) error {
+	ext := strings.ToLower(filepath.Ext(path))
+	if !outputFormats[ext] {
+		return fmt.Errorf("unknown output format '%s' for %s", ext, path)
+	}
+	return nil
+}
+
+// Writes the laid out graph to path, in the format picked by its extension
+func writeOutput(path string, graph PosGraph, data *GraphData, directed bool, opts OutputOptions) error {
+	if err := checkOutputPath(path); err != nil {
+		return err
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	switch ext {
+	case ".png":
+		err = writePNG(file, graph, directed, opts.Render)
+	case ".json":
+		err = writeNodeLinkJSON(file, graph, data, directed)
+	case ".svg":
+		err = writeSVG(file, graph, directed, opts.Render)
+	case ".pdf":
+		err = writePDF(file, graph, directed, opts.PDF)
+	case ".tex":
+		err = writeTikZ(file, graph, directed, opts.TikZ)
+	case ".dot", ".gv":
+		err = writeDOT(file, graph, data, directed)
+	case ".graphml":
+		err = writeGraphML(file, graph, data, directed)
+	case ".gexf":
+		err = writeGEXF(file, graph, data, directed)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("writing %s: %v", path, err)
+	}
+	return nil
+}
+
+// Ids to write nodes under: their names, or n0, n1, ... if the names aren't unique (Pajek
+// labels, for one, don't have to be)
+func exportIDs(graph PosGraph) []string {
+	ids := make([]string, len(graph))
+	seen := make(map[string]bool, len(graph))
+	for i, node := range graph {
+		if seen[node.Name] {
+			for i := range ids {
+				ids[i] = "n" + strconv.Itoa(i)
+			}
+			return ids
+		}
+		seen[node.Name] = true
+		ids[i] = node.Name
+	}
+	return ids
+}
+
+// Attributes to write for the edge u -> v, stored as the j-th edge of u. For graphs whose
+// weights didn't come from attributes, such as weighted edge lists, the weight is added.
+func (d *GraphData) exportEdgeAttrs(u, v, j int) Attrs {
+	attrs := d.edgeAttrs(u, v)
+	weighted := d.Weights != nil || (d.CSR != nil && d.CSR.Weights != nil)
+	if _, ok := attrs["weight"]; ok || !weighted {
+		return attrs
+	}
+	out := Attrs{"weight": strconv.FormatFloat(d.adjacency().Weight(u, j), 'g', -1, 64)}
+	for k, val := range attrs {
+		out[k] = val
+	}
+	return out
+}
+
+// Node diameter in layout units for formats that store one, in the same proportion to the
+// drawing as RenderPNG's nodes
+func exportNodeSize(graph PosGraph) float64 {
+	boundary := getBoundary(graph)
+	span := max(boundary.Right-boundary.Left, boundary.Top-boundary.Bottom)
+	return float64(2*nodeRadius) / 2000 * float64(span)
+}
+
+// Attribute names used by any of attrs, sorted
+func attrNames(attrs ...Attrs) []string {
+	seen := make(map[string]bool)
+	for _, a := range attrs {
+		for k := range a {
+			seen[k] = true
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for k := range seen {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+/***** GEXF output *****/
+
+// Declares an <attribute> per attribute name in class ("node" or "edge") and returns their
+// ids by name. Attributes whose values are all numbers are declared as doubles.
+func writeGEXFAttributes(out io.Writer, class string, attrs []Attrs) map[string]string {
+	names := attrNames(attrs...)
+	ids := make(map[string]string)
+	if len(names) == 0 {
+		return ids
+	}
+	fmt.Fprintf(out, "    <attributes class=\"%s\">\n", class)
+	for i, name := range names {
+		typ := "double"
+		for _, a := range attrs {
+			if v, ok := a[name]; ok {
+				if _, err := strconv.ParseFloat(v, 64); err != nil {
+					typ = "string"
+					break
+				}
+			}
+		}
+		ids[name] = strconv.Itoa(i)
+		fmt.Fprintf(out, "      <attribute id=\"%d\" title=\"%s\" type=\"%s\"/>\n", i, xmlEscape(name), typ)
+	}
+	fmt.Fprintf(out, "    </attributes>\n")
+	return ids
+}
+
+func writeGEXFAttValues(out io.Writer, attrs Attrs, ids map[string]string) {
+	if len(attrs) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "<attvalues>")
+	for _, name := range attrNames(attrs) {
+		fmt.Fprintf(out, "<attvalue for=\"%s\" value=\"%s\"/>", ids[name], xmlEscape(attrs[name]))
+	}
+	fmt.Fprintf(out, "</attvalues>")
+}
+
+// Writes the laid out graph as GEXF 1.2 with the attributes from the input file, and each
+// node's position and size in viz:position and viz:size so Gephi opens it laid out. Edge
+// weights go in GEXF's own weight field rather than an attribute.
+func writeGEXF(w io.Writer, graph PosGraph, data *GraphData, directed bool) error {
+	out := bufio.NewWriter(w)
+	ids := exportIDs(graph)
+	size := exportNodeSize(graph)
+
+	nodeAttrs := make([]Attrs, len(graph))
+	for i := range graph {
+		if i < len(data.Attrs.Nodes) {
+			nodeAttrs[i] = data.Attrs.Nodes[i]
+		}
+	}
+	var edgeAttrs []Attrs
+	var weights []string
+	forEachEdge(graph, directed, func(u, v, j int) {
+		attrs := make(Attrs)
+		for k, val := range data.exportEdgeAttrs(u, v, j) {
+			attrs[k] = val
+		}
+		weight, ok := attrs["weight"]
+		if _, err := strconv.ParseFloat(weight, 64); ok && err == nil {
+			delete(attrs, "weight")
+		} else {
+			weight = ""
+		}
+		edgeAttrs = append(edgeAttrs, attrs)
+		weights = append(weights, weight)
+	})
+
+	edgeType := "undirected"
+	if directed {
+		edgeType = "directed"
+	}
+	fmt.Fprintf(out, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprintf(out, "<gexf xmlns=\"http://www.gexf.net/1.2draft\" xmlns:viz=\"http://www.gexf.net/1.2draft/viz\" version=\"1.2\">\n")
+	fmt.Fprintf(out, "  <graph mode=\"static\" defaultedgetype=\"%s\">\n", edgeType)
+	nodeIDs := writeGEXFAttributes(out, "node", nodeAttrs)
+	edgeIDs := writeGEXFAttributes(out, "edge", edgeAttrs)
+
+	fmt.Fprintf(out, "    <nodes>\n")
+	for i, node := range graph {
+		fmt.Fprintf(out, "      <node id=\"%s\" label=\"%s\">", xmlEscape(ids[i]), xmlEscape(node.Name))
+		writeGEXFAttValues(out, nodeAttrs[i], nodeIDs)
+		fmt.Fprintf(out, "<viz:position x=\"%g\" y=\"%g\" z=\"0\"/><viz:size value=\"%g\"/>", node.X, node.Y, size/2*node.sizeFactor())
+		fill := node.fill()
+		fmt.Fprintf(out, "<viz:color r=\"%d\" g=\"%d\" b=\"%d\"/></node>\n", fill.R, fill.G, fill.B)
+	}
+	fmt.Fprintf(out, "    </nodes>\n")
+
+	fmt.Fprintf(out, "    <edges>\n")
+	e := 0
+	forEachEdge(graph, directed, func(u, v, _ int) {
+		fmt.Fprintf(out, "      <edge id=\"%d\" source=\"%s\" target=\"%s\"", e, xmlEscape(ids[u]), xmlEscape(ids[v]))
+		if weights[e] != "" {
+			fmt.Fprintf(out, " weight=\"%s\"", weights[e])
+		}
+		fmt.Fprintf(out, ">")
+		writeGEXFAttValues(out, edgeAttrs[e], edgeIDs)
+		fmt.Fprintf(out, "</edge>\n")
+		e++
+	})
+	fmt.Fprintf(out, "    </edges>\n")
+	fmt.Fprintf(out, "  </graph>\n")
+	fmt.Fprintf(out, "</gexf>\n")
+	return out.Flush()
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestWriteGEXF(t *testing.T) {
+	data := &GraphData{
+		Graph:   Graph{{1}, {0}},
+		NodeIDs: []string{"a", "b"},
+		Attrs: AttrTable{
+			Nodes: []Attrs{{"group": "1"}, {"group": "two"}},
+			Edges: map[[2]int]Attrs{{0, 1}: {"weight": "1.5", "kind": "road"}},
+		},
+	}
+	graph := augmentGraph(data, []Point{{X: 1, Y: 2}, {X: 3, Y: 4}})
+	var buf bytes.Buffer
+	if err := writeGEXF(&buf, graph, data, false); err != nil {
+		t.Fatal(err)
+	}
+	gexf := buf.String()
+
+	var doc struct {
+		Nodes []struct {
+			ID       string `xml:"id,attr"`
+			Position struct {
+				X float64 `xml:"x,attr"`
+				Y float64 `xml:"y,attr"`
+			} `xml:"position"`
+		} `xml:"graph>nodes>node"`
+		Edges []struct {
+			Weight string `xml:"weight,attr"`
+		} `xml:"graph>edges>edge"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid GEXF: %v\n%s", err, gexf)
+	}
+	if len(doc.Nodes) != 2 || doc.Nodes[1].ID != "b" || doc.Nodes[1].Position.X != 3 || doc.Nodes[1].Position.Y != 4 {
+		t.Errorf("nodes = %+v", doc.Nodes)
+	}
+	if len(doc.Edges) != 1 || doc.Edges[0].Weight != "1.5" {
+		t.Errorf("edges = %+v, want one edge of weight 1.5", doc.Edges)
+	}
+	for _, want := range []string{
+		`<attribute id="0" title="group" type="string"/>`,
+		`<attribute id="0" title="kind" type="string"/>`,
+		`<attvalue for="0" value="road"/>`,
+	} {
+		if !strings.Contains(gexf, want) {
+			t.Errorf("GEXF is missing %s:\n%s", want, gexf)
+		}
+	}
+	if strings.Contains(gexf, `title="weight"`) {
+		t.Errorf("weight written as an attribute as well")
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/***** GraphML input *****/
+
+type graphmlData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphmlKey struct {
+	ID      string `xml:"id,attr"`
+	For     string `xml:"for,attr"`
+	Name    string `xml:"attr.name,attr"`
+	Default string `xml:"default"`
+	// Set on yFiles graphics keys, whose data is markup rather than a value
+	YFilesType string `xml:"yfiles.type,attr"`
+}
+
+type graphmlNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphmlData `xml:"data"`
+}
+
+type graphmlEdge struct {
+	Source   string        `xml:"source,attr"`
+	Target   string        `xml:"target,attr"`
+	Directed string        `xml:"directed,attr"`
+	Data     []graphmlData `xml:"data"`
+}
+
+type graphmlGraph struct {
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphmlNode `xml:"node"`
+	Edges       []graphmlEdge `xml:"edge"`
+}
+
+type graphmlDoc struct {
+	Keys   []graphmlKey   `xml:"key"`
+	Graphs []graphmlGraph `xml:"graph"`
+}
+
+// Resolves <data> elements to attribute names using the <key> declarations, filling in key
+// defaults for anything the element doesn't set.
+func graphmlAttrs(data []graphmlData, keys map[string]graphmlKey, domain string) Attrs {
+	attrs := make(Attrs)
+	for _, key := range keys {
+		if key.Default != "" && (key.For == domain || key.For == "all") {
+			attrs[key.Name] = strings.TrimSpace(key.Default)
+		}
+	}
+	for _, d := range data {
+		name := d.Key
+		if key, ok := keys[d.Key]; ok && key.YFilesType != "" {
+			continue
+		} else if ok && key.Name != "" {
+			name = key.Name
+		}
+		attrs[name] = strings.TrimSpace(d.Value)
+	}
+	return attrs
+}
+
+// Reads the first graph of a GraphML document. Node ids are kept in the order the nodes are
+// declared, and edges that refer to undeclared nodes add them implicitly. Nested graphs and
+// hyperedges are ignored.
+func readGraphML(r io.Reader) (*GraphData, error) {
+	var doc graphmlDoc
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid GraphML: %v", err)
+	}
+	if len(doc.Graphs) == 0 {
+		return nil, fmt.Errorf("invalid GraphML: no <graph> element")
+	}
+	g := doc.Graphs[0]
+
+	keys := make(map[string]graphmlKey)
+	for _, key := range doc.Keys {
+		if key.Name == "" {
+			key.Name = key.ID
+		}
+		keys[key.ID] = key
+	}
+
+	out := &GraphData{
+		Directed: g.EdgeDefault == "directed",
+		Attrs:    AttrTable{Edges: make(map[[2]int]Attrs)},
+	}
+	idsToIndices := make(map[string]int)
+	addNode := func(id string, attrs Attrs) int {
+		i, ok := idsToIndices[id]
+		if !ok {
+			i = len(out.Graph)
+			idsToIndices[id] = i
+			out.Graph = append(out.Graph, nil)
+			out.NodeIDs = append(out.NodeIDs, id)
+			out.Attrs.Nodes = append(out.Attrs.Nodes, graphmlAttrs(nil, keys, "node"))
+		}
+		for k, v := range attrs {
+			out.Attrs.Nodes[i][k] = v
+		}
+		return i
+	}
+
+	for _, node := range g.Nodes {
+		if node.ID == "" {
+			return nil, fmt.Errorf("invalid GraphML: node without an id")
+		}
+		addNode(node.ID, graphmlAttrs(node.Data, keys, "node"))
+	}
+
+	for _, edge := range g.Edges {
+		if edge.Source == "" || edge.Target == "" {
+			return nil, fmt.Errorf("invalid GraphML: edge without source or target")
+		}
+		u := addNode(edge.Source, nil)
+		v := addNode(edge.Target, nil)
+		directed := out.Directed
+		if edge.Directed != "" {
+			directed = edge.Directed == "true"
+		}
+		out.Graph[u] = append(out.Graph[u], v)
+		if !directed {
+			out.Graph[v] = append(out.Graph[v], u)
+		}
+		out.Attrs.Edges[[2]int{u, v}] = graphmlAttrs(edge.Data, keys, "edge")
+	}
+
+	return out, nil
+}
+
+/***** GraphML output *****/
+
+// Declares a <key> per attribute name in domain ("node" or "edge") and returns their ids by
+// name. Attributes whose values are all numbers are declared as doubles.
+func writeGraphMLKeys(out io.Writer, domain, prefix string, attrs []Attrs) map[string]string {
+	ids := make(map[string]string)
+	for i, name := range attrNames(attrs...) {
+		typ := "double"
+		for _, a := range attrs {
+			if v, ok := a[name]; ok {
+				if _, err := strconv.ParseFloat(v, 64); err != nil {
+					typ = "string"
+					break
+				}
+			}
+		}
+		ids[name] = prefix + strconv.Itoa(i)
+		fmt.Fprintf(out, "  <key id=\"%s\" for=\"%s\" attr.name=\"%s\" attr.type=\"%s\"/>\n", ids[name], domain, xmlEscape(name), typ)
+	}
+	return ids
+}
+
+func writeGraphMLData(out io.Writer, indent string, attrs Attrs, keys map[string]string) {
+	for _, name := range attrNames(attrs) {
+		fmt.Fprintf(out, "%s<data key=\"%s\">%s</data>\n", indent, keys[name], xmlEscape(attrs[name]))
+	}
+}
+
+// Writes the laid out graph as GraphML with the attributes from the input file, plus
+// yFiles node graphics holding each node's position and size so yEd opens it laid out.
+// yFiles' y axis points down, so y is flipped to keep the picture the right way up.
+func writeGraphML(w io.Writer, graph PosGraph, data *GraphData, directed bool) error {
+	out := bufio.NewWriter(w)
+	ids := exportIDs(graph)
+	size := exportNodeSize(graph)
+
+	nodeAttrs := make([]Attrs, len(graph))
+	for i := range graph {
+		if i < len(data.Attrs.Nodes) {
+			nodeAttrs[i] = data.Attrs.Nodes[i]
+		}
+	}
+	var edgeAttrs []Attrs
+	forEachEdge(graph, directed, func(u, v, j int) {
+		edgeAttrs = append(edgeAttrs, data.exportEdgeAttrs(u, v, j))
+	})
+
+	fmt.Fprintf(out, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprintf(out, "<graphml xmlns=\"http://graphml.graphdrawing.org/xmlns\" xmlns:y=\"http://www.yworks.com/xml/graphml\">\n")
+	nodeKeys := writeGraphMLKeys(out, "node", "n", nodeAttrs)
+	edgeKeys := writeGraphMLKeys(out, "edge", "e", edgeAttrs)
+	fmt.Fprintf(out, "  <key id=\"graphics\" for=\"node\" yfiles.type=\"nodegraphics\"/>\n")
+	edgeDefault := "undirected"
+	if directed {
+		edgeDefault = "directed"
+	}
+	fmt.Fprintf(out, "  <graph id=\"G\" edgedefault=\"%s\">\n", edgeDefault)
+
+	for i, node := range graph {
+		fmt.Fprintf(out, "    <node id=\"%s\">\n", xmlEscape(ids[i]))
+		writeGraphMLData(out, "      ", nodeAttrs[i], nodeKeys)
+		fmt.Fprintf(out, "      <data key=\"graphics\"><y:ShapeNode>")
+		d := size * node.sizeFactor()
+		fmt.Fprintf(out, "<y:Geometry x=\"%g\" y=\"%g\" width=\"%g\" height=\"%g\"/>",
+			float64(node.X)-d/2, -float64(node.Y)-d/2, d, d)
+		fmt.Fprintf(out, "<y:Fill color=\"%s\"/><y:Shape type=\"ellipse\"/>", strings.ToUpper(svgColor(node.fill())))
+		fmt.Fprintf(out, "<y:NodeLabel>%s</y:NodeLabel></y:ShapeNode></data>\n", xmlEscape(node.Name))
+		fmt.Fprintf(out, "    </node>\n")
+	}
+	e := 0
+	forEachEdge(graph, directed, func(u, v, _ int) {
+		fmt.Fprintf(out, "    <edge source=\"%s\" target=\"%s\">\n", xmlEscape(ids[u]), xmlEscape(ids[v]))
+		writeGraphMLData(out, "      ", edgeAttrs[e], edgeKeys)
+		fmt.Fprintf(out, "    </edge>\n")
+		e++
+	})
+	fmt.Fprintf(out, "  </graph>\n")
+	fmt.Fprintf(out, "</graphml>\n")
+	return out.Flush()
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testGraphML = `<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+  <key id="d0" for="node" attr.name="color" attr.type="string"><default>gray</default></key>
+  <key id="d1" for="edge" attr.name="weight" attr.type="double"/>
+  <graph id="G" edgedefault="directed">
+    <node id="a"><data key="d0">red</data></node>
+    <node id="b"/>
+    <edge source="a" target="b"><data key="d1">2.5</data></edge>
+    <edge source="b" target="c" directed="false"/>
+  </graph>
+</graphml>`
+
+func TestReadGraphML(t *testing.T) {
+	data, err := readGraphML(strings.NewReader(testGraphML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !data.Directed {
+		t.Errorf("expected a directed graph")
+	}
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(data.NodeIDs, want) {
+		t.Errorf("node ids = %v, want %v", data.NodeIDs, want)
+	}
+	if want := (Graph{{1}, {2}, {1}}); !reflect.DeepEqual(data.Graph, want) {
+		t.Errorf("graph = %v, want %v", data.Graph, want)
+	}
+	if c := data.Attrs.Nodes[0]["color"]; c != "red" {
+		t.Errorf("node a color = %q, want red", c)
+	}
+	if c := data.Attrs.Nodes[1]["color"]; c != "gray" {
+		t.Errorf("node b color = %q, want the default gray", c)
+	}
+	if w := data.Attrs.Edges[[2]int{0, 1}]["weight"]; w != "2.5" {
+		t.Errorf("edge a->b weight = %q, want 2.5", w)
+	}
+}
+
+func TestWriteGraphML(t *testing.T) {
+	// weights from an edge list, which has no attributes
+	data, err := readEdgeListPar([]byte("a b 2\nb c\n"), false, 1, EdgeListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.Attrs.Nodes = []Attrs{{"kind": "x & y"}, nil, nil}
+	graph := augmentGraph(data, []Point{{X: 0, Y: 0}, {X: 10, Y: 20}, {X: 20, Y: 0}})
+	var buf bytes.Buffer
+	if err := writeGraphML(&buf, graph, data, false); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `<y:Geometry x="9.88" y="-20.12" width="0.24" height="0.24"/>`) {
+		t.Errorf("missing yFiles geometry for b:\n%s", buf.String())
+	}
+
+	back, err := readGraphML(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := back.weightsFromAttrs(); err != nil {
+		t.Fatal(err)
+	}
+	if back.Directed || !reflect.DeepEqual(back.NodeIDs, data.NodeIDs) || !reflect.DeepEqual(back.Graph, data.Graph) {
+		t.Errorf("round trip gave %v %v, want %v %v", back.NodeIDs, back.Graph, data.NodeIDs, data.Graph)
+	}
+	if !reflect.DeepEqual(back.Weights, data.Weights) {
+		t.Errorf("round trip weights = %v, want %v", back.Weights, data.Weights)
+	}
+	// the yFiles graphics aren't read back as an attribute
+	if want := (Attrs{"kind": "x & y"}); !reflect.DeepEqual(back.Attrs.Nodes[0], want) {
+		t.Errorf("attributes of a = %v, want %v", back.Attrs.Nodes[0], want)
+	}
+}
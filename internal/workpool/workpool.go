@@ -0,0 +1,76 @@
+// Package workpool runs ranges of work on a fixed set of goroutines.
+package workpool
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+/***** Worker pool *****/
+
+// A fixed set of goroutines that the phases of a layout hand ranges of work to, so that an
+// iteration doesn't start goroutines of its own. The goroutine calling Run works too, so a pool
+// of n workers starts n-1 goroutines, and a pool of 1 runs everything in place.
+type Pool struct {
+	workers int
+	jobs    chan *poolJob
+}
+
+// A range [0, n) being worked through in chunks of grain. Workers claim the next chunk from
+// the shared counter until none are left, so a worker that finishes early takes on more chunks
+// instead of waiting for a slow one.
+type poolJob struct {
+	n, grain int
+	fn       func(start, end int)
+	next     atomic.Int64
+	wg       sync.WaitGroup
+}
+
+// A pool of workers goroutines, counting the caller of Run
+func New(workers int) *Pool {
+	p := &Pool{workers: max(workers, 1), jobs: make(chan *poolJob)}
+	for w := 1; w < p.workers; w++ {
+		go func() {
+			for job := range p.jobs {
+				job.work()
+			}
+		}()
+	}
+	return p
+}
+
+func (job *poolJob) work() {
+	defer job.wg.Done()
+	for {
+		start := int(job.next.Add(int64(job.grain))) - job.grain
+		if start >= job.n {
+			return
+		}
+		job.fn(start, min(start+job.grain, job.n))
+	}
+}
+
+// Runs fn over [0, n) in chunks of grain, or about 4 chunks per worker if grain is 0, and waits
+// for it to finish. Chunks can run in any order and on any worker. fn must not call Run itself,
+// since the pool's workers may all be busy with the outer job.
+func (p *Pool) Run(n, grain int, fn func(start, end int)) {
+	if n <= 0 {
+		return
+	}
+	if grain <= 0 {
+		grain = max((n+4*p.workers-1)/(4*p.workers), 1)
+	}
+	job := &poolJob{n: n, grain: grain, fn: fn}
+	helpers := min(p.workers, (n+grain-1)/grain) - 1
+	job.wg.Add(helpers + 1)
+	for w := 0; w < helpers; w++ {
+		p.jobs <- job
+	}
+	job.work()
+	job.wg.Wait()
+}
+
+// Stops the pool's goroutines
+func (p *Pool) Close() {
+	close(p.jobs)
+}
@@ -0,0 +1,30 @@
+package workpool
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestWorkerPool(t *testing.T) {
+	for _, workers := range []int{1, 3, 8} {
+		pool := New(workers)
+		for _, test := range []struct{ n, grain int }{{0, 0}, {1, 0}, {10, 3}, {100, 0}, {1000, 1}, {5, 100}} {
+			counts := make([]atomic.Int32, test.n)
+			// Jobs run one after another on the same goroutines
+			for round := 0; round < 3; round++ {
+				pool.Run(test.n, test.grain, func(start, end int) {
+					for i := start; i < end; i++ {
+						counts[i].Add(1)
+					}
+				})
+			}
+			for i := range counts {
+				if c := counts[i].Load(); c != 3 {
+					t.Errorf("workers=%d n=%d grain=%d: index %d run %d times in 3 jobs",
+						workers, test.n, test.grain, i, c)
+				}
+			}
+		}
+		pool.Close()
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sync"
+
+	"github.com/go-text/typesetting/di"
+	"github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/language"
+	"github.com/go-text/typesetting/shaping"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+/***** Node labels *****/
+
+var labelColor = color.RGBA{0, 0, 0, 255}
+
+// Labels centered on a node are drawn in white to stand out against nodeColor
+var labelInsideColor = color.RGBA{255, 255, 255, 255}
+
+// Label positions relative to the node
+var labelPositions = map[string]bool{"right": true, "below": true, "center": true}
+
+// Nodes are shaped and rasterized in chunks of this many, one goroutine per chunk
+const labelChunkSize = 256
+
+// The Go font, parsed once. Faces aren't safe for concurrent use, but the Font is, so each
+// goroutine makes its own Face from it.
+var labelFont = sync.OnceValue(func() *font.Font {
+	face, err := font.ParseTTF(bytes.NewReader(goregular.TTF))
+	if err != nil {
+		// The font is built in, so this can't happen
+		panic(err)
+	}
+	return face.Font
+})
+
+// Script of the first letter of text that belongs to one, for the shaper
+func textScript(text []rune) language.Script {
+	for _, r := range text {
+		if s := language.LookupScript(r); s != language.Common && s != language.Inherited {
+			return s
+		}
+	}
+	return language.Latin
+}
+
+func fixedToFloat(x fixed.Int26_6) float32 {
+	return float32(x) / 64
+}
+
+// Shapes text at size pixels and rasterizes it into a coverage mask holding its line box, with
+// the origin at the top left
+func rasterizeLabel(shaper *shaping.HarfbuzzShaper, face *font.Face, text string, size float64) *image.Alpha {
+	runes := []rune(text)
+	out := shaper.Shape(shaping.Input{
+		Text:      runes,
+		RunStart:  0,
+		RunEnd:    len(runes),
+		Direction: di.DirectionLTR,
+		Face:      face,
+		Size:      fixed.Int26_6(math.Round(size * 64)),
+		Script:    textScript(runes),
+		Language:  language.DefaultLanguage(),
+	})
+	ascent := fixedToFloat(out.LineBounds.Ascent)
+	w := int(math.Ceil(float64(fixedToFloat(out.Advance)))) + 1
+	h := int(math.Ceil(float64(ascent-fixedToFloat(out.LineBounds.Descent)))) + 1
+	mask := image.NewAlpha(image.Rect(0, 0, max(w, 1), max(h, 1)))
+
+	z := vector.NewRasterizer(mask.Rect.Dx(), mask.Rect.Dy())
+	scale := float32(size) / float32(face.Upem())
+	penX := float32(0)
+	for _, g := range out.Glyphs {
+		outline, ok := face.GlyphData(g.GlyphID).(font.GlyphOutline)
+		// Font units have y going up; the mask's go down from the top of the line box
+		x0, y0 := penX+fixedToFloat(g.XOffset), ascent-fixedToFloat(g.YOffset)
+		pt := func(p ot.SegmentPoint) (float32, float32) {
+			return x0 + p.X*scale, y0 - p.Y*scale
+		}
+		started := false
+		for _, seg := range outline.Segments {
+			switch seg.Op {
+			case ot.SegmentOpMoveTo:
+				if started {
+					z.ClosePath()
+				}
+				z.MoveTo(pt(seg.Args[0]))
+				started = true
+			case ot.SegmentOpLineTo:
+				z.LineTo(pt(seg.Args[0]))
+			case ot.SegmentOpQuadTo:
+				bx, by := pt(seg.Args[0])
+				cx, cy := pt(seg.Args[1])
+				z.QuadTo(bx, by, cx, cy)
+			case ot.SegmentOpCubeTo:
+				bx, by := pt(seg.Args[0])
+				cx, cy := pt(seg.Args[1])
+				dx, dy := pt(seg.Args[2])
+				z.CubeTo(bx, by, cx, cy, dx, dy)
+			}
+		}
+		if ok && started {
+			z.ClosePath()
+		}
+		penX += fixedToFloat(g.XAdvance)
+	}
+	z.Draw(mask, mask.Rect, image.Opaque, image.Point{})
+	return mask
+}
+
+// Whether labels should be drawn on a w x h image of graph: they're on, and there are at least
+// LabelMinSpacing pixels per node on average. In the GUI, this is what turns labels on as the
+// window grows.
+func (o RenderOptions) showLabels(graph PosGraph, w, h int) bool {
+	if !o.Labels || len(graph) == 0 || o.FontSize <= 0 {
+		return false
+	}
+	spacing := math.Sqrt(float64(w) * float64(h) / float64(len(graph)))
+	return spacing >= o.LabelMinSpacing*o.Scale
+}
+
+// Where the top left of a w x h label goes for a node of radius r at p, on an image with the
+// given bounds. A label that would run off the image goes on the other side of the node, and is
+// then shifted inside if it still doesn't fit, so labels near the edges aren't cut off.
+func placeLabel(p image.Point, w, h, r int, position string, bounds image.Rectangle) image.Point {
+	gap := max(r/3, 1)
+	var q image.Point
+	switch position {
+	case "below":
+		q = image.Pt(p.X-w/2, p.Y+r+gap)
+		if q.Y+h > bounds.Max.Y {
+			q.Y = p.Y - r - gap - h
+		}
+	case "center":
+		q = image.Pt(p.X-w/2, p.Y-h/2)
+	default:
+		q = image.Pt(p.X+r+gap, p.Y-h/2)
+		if q.X+w > bounds.Max.X {
+			q.X = p.X - r - gap - w
+		}
+	}
+	q.X = max(min(q.X, bounds.Max.X-w), bounds.Min.X)
+	q.Y = max(min(q.Y, bounds.Max.Y-h), bounds.Min.Y)
+	return q
+}
+
+// Rasterizes every node's label (or name) and returns the masks with the boxes they are to be
+// drawn in, node i's at index i, beside a node of radius radii[i] at pos[i] and inside bounds.
+// The work is split into chunks of nodes in parallel.
+func rasterizeLabels(graph PosGraph, pos []image.Point, radii []int, bounds image.Rectangle, opts RenderOptions) ([]*image.Alpha, []image.Rectangle) {
+	f := labelFont()
+	size := opts.FontSize * opts.Scale
+	masks := make([]*image.Alpha, len(graph))
+	boxes := make([]image.Rectangle, len(graph))
+	var wg sync.WaitGroup
+	for start := 0; start < len(graph); start += labelChunkSize {
+		end := min(start+labelChunkSize, len(graph))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			face := font.NewFace(f)
+			var shaper shaping.HarfbuzzShaper
+			for i := start; i < end; i++ {
+				text := graph[i].Label
+				if text == "" {
+					text = graph[i].Name
+				}
+				if text == "" {
+					continue
+				}
+				masks[i] = rasterizeLabel(&shaper, face, text, size)
+				w, h := masks[i].Rect.Dx(), masks[i].Rect.Dy()
+				boxes[i] = image.Rectangle{Max: image.Pt(w, h)}.Add(placeLabel(pos[i], w, h, radii[i], opts.LabelPosition, bounds))
+			}
+		}()
+	}
+	wg.Wait()
+	return masks, boxes
+}
+
+// Draws the labels of the nodes listed in nodes onto img, which may be a tile
+func drawLabels(img *image.RGBA, masks []*image.Alpha, boxes []image.Rectangle, nodes []int, opts RenderOptions) {
+	c := labelColor
+	if opts.LabelPosition == "center" {
+		c = labelInsideColor
+	}
+	src := image.NewUniform(c)
+	for _, i := range nodes {
+		if masks[i] != nil {
+			draw.DrawMask(img, boxes[i], src, image.Point{}, masks[i], image.Point{}, draw.Over)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+func TestShowLabels(t *testing.T) {
+	opts := defaultRenderOptions
+	opts.Labels = true
+	// 4 nodes on 200 x 200 have 100 pixels each
+	if !opts.showLabels(testgraph, 200, 200) {
+		t.Errorf("labels hidden at 100 pixels per node")
+	}
+	opts.LabelMinSpacing = 101
+	if opts.showLabels(testgraph, 200, 200) {
+		t.Errorf("labels shown below the minimum spacing")
+	}
+	opts.Scale = 0.5
+	if !opts.showLabels(testgraph, 200, 200) {
+		t.Errorf("minimum spacing not scaled")
+	}
+	opts.Labels = false
+	if opts.showLabels(testgraph, 200, 200) {
+		t.Errorf("labels shown when off")
+	}
+}
+
+// Counts pixels in r darker than mid gray, which only label text is in these tests
+func darkPixels(img *image.RGBA, r image.Rectangle) int {
+	n := 0
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			if c.R < 128 && c.G < 128 && c.B < 128 {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestDrawLabels(t *testing.T) {
+	graph := PosGraph{
+		{X: 0, Y: 0, Name: "a", Label: "Wide label"},
+		{X: 1, Y: 1, Name: "b", Label: "Long name"},
+	}
+	opts := defaultRenderOptions
+	opts.Width, opts.Height, opts.Margin = 300, 300, 20
+	opts.LabelMinSpacing = 0
+
+	img := renderImage(graph, false, opts)
+	if n := darkPixels(img, img.Rect); n != 0 {
+		t.Errorf("%d dark pixels without labels", n)
+	}
+
+	opts.Labels = true
+	img = renderImage(graph, false, opts)
+	// Node a is at the bottom left, at (32, 268); its label starts to its right
+	if n := darkPixels(img, image.Rect(45, 255, 150, 285)); n < 50 {
+		t.Errorf("only %d dark pixels where node a's label should be", n)
+	}
+	if n := darkPixels(img, image.Rect(281, 255, 300, 285)); n != 0 {
+		t.Errorf("%d dark pixels right of node a's label", n)
+	}
+	// Node b is at the top right, at (268, 32), so its label goes to its left instead of off the
+	// edge
+	if n := darkPixels(img, image.Rect(256, 0, 300, 300)); n != 0 {
+		t.Errorf("%d dark pixels right of node b", n)
+	}
+	if n := darkPixels(img, image.Rect(180, 20, 256, 45)); n < 50 {
+		t.Errorf("only %d dark pixels where node b's label should be", n)
+	}
+
+	for _, tileSize := range []int{7, 64} {
+		tiled := image.NewRGBA(img.Rect)
+		drawGraphTiled(tiled, graph, false, opts, tileSize)
+		if !bytes.Equal(img.Pix, tiled.Pix) {
+			t.Errorf("labels drawn differently with %d pixel tiles", tileSize)
+		}
+	}
+}
+
+func TestPlaceLabel(t *testing.T) {
+	bounds := image.Rect(0, 0, 200, 200)
+	tests := []struct {
+		p        image.Point
+		position string
+		want     image.Point
+	}{
+		{image.Pt(100, 100), "right", image.Pt(116, 95)},
+		{image.Pt(100, 100), "below", image.Pt(80, 116)},
+		{image.Pt(100, 100), "center", image.Pt(80, 95)},
+		// off the right or bottom edge: the other side of the node
+		{image.Pt(170, 100), "right", image.Pt(114, 95)},
+		{image.Pt(100, 190), "below", image.Pt(80, 164)},
+		// still off the image: shifted inside
+		{image.Pt(10, 100), "below", image.Pt(0, 116)},
+		{image.Pt(195, 198), "center", image.Pt(160, 190)},
+		{image.Pt(30, 2), "right", image.Pt(46, 0)},
+	}
+	for _, test := range tests {
+		if got := placeLabel(test.p, 40, 10, 12, test.position, bounds); got != test.want {
+			t.Errorf("placeLabel(%v, %s) = %v, want %v", test.p, test.position, got, test.want)
+		}
+	}
+}
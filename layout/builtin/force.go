@@ -0,0 +1,456 @@
+package builtin
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/clarkep/ppa-final/internal/workpool"
+	"github.com/clarkep/ppa-final/layout"
+	"github.com/schollz/progressbar/v3"
+)
+
+func clamp(val, min, max float64) float64 {
+	if val < min {
+		return min
+	}
+	if val > max {
+		return max
+	}
+	return val
+}
+
+func assignRandomPositions(nodes layout.Adjacency, width, height float64, rng *rand.Rand) []layout.Point {
+	n := nodes.NumNodes()
+	if n == 0 {
+		return make([]layout.Point, 0)
+	}
+
+	// Initialize positions randomly
+	positions := make([]layout.Point, n)
+	for i := 0; i < n; i++ {
+		positions[i] = layout.Point{
+			X: rng.Float64() * width,
+			Y: rng.Float64() * height,
+		}
+	}
+
+	return positions
+}
+
+// What a force layout reports when it's done
+type ForceResult struct {
+	// Iterations run, fewer than asked for if the layout converged
+	Iterations int
+	// Sum of the squared forces on the nodes in the last iteration
+	Energy    float64
+	Converged bool
+}
+
+// Each time the energy goes up, the adaptive schedule multiplies the temperature by this, and
+// after five iterations in a row where it goes down, divides by it [1]
+const adaptiveCooling = 0.9
+
+// The temperature of a force layout over its iterations. Without a tolerance it falls linearly
+// over the iterations, as set by Temperature and Cooling. With one, the layout runs until it
+// converges, so the temperature follows the energy instead [1].
+type coolingSchedule struct {
+	t, start, rate float64
+	adaptive       bool
+	energy         float64
+	progress       int
+}
+
+func (o ForceOptions) cooling() *coolingSchedule {
+	t, rate := o.temperature()
+	return &coolingSchedule{t: t, start: t, rate: rate, adaptive: o.Tolerance > 0, energy: math.Inf(1)}
+}
+
+// Moves on to the next iteration, after one that left the system with the given energy
+func (c *coolingSchedule) cool(energy float64) {
+	switch {
+	case !c.adaptive:
+		c.t -= c.rate
+	case energy < c.energy:
+		c.progress++
+		if c.progress >= 5 {
+			c.progress = 0
+			c.t = math.Min(c.t/adaptiveCooling, c.start)
+		}
+	default:
+		c.progress = 0
+		c.t *= adaptiveCooling
+	}
+	c.energy = energy
+}
+
+// Whether an iteration that moved the nodes this far in total is the last one: the layout
+// has converged once the nodes move less than Tolerance times the ideal edge length k on average
+func (o ForceOptions) converged(moved float64, n int, k float64) bool {
+	return o.Tolerance > 0 && moved < o.Tolerance*float64(n)*k
+}
+
+// Adds up xs in order
+func sum(xs []float64) float64 {
+	total := 0.0
+	for _, x := range xs {
+		total += x
+	}
+	return total
+}
+
+func forceDirectedLayout(nodes layout.Adjacency, opts ForceOptions) ([]layout.Point, ForceResult) {
+	n := nodes.NumNodes()
+	iterations, width, height := opts.Iterations, opts.Width, opts.Height
+	positions := assignRandomPositions(nodes, width, height, opts.rand())
+
+	k := math.Sqrt((width * height) / float64(n))
+	cooling := opts.cooling()
+	epsilon := 1e-6
+
+	var result ForceResult
+	bar := progressbar.Default(int64(iterations))
+	for iter := 0; iter < iterations; iter++ {
+		bar.Add(1)
+		t := cooling.t
+		displacements := make([]layout.Point, n)
+
+		// Calculate repulsive forces
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				delta := positions[i].Sub(positions[j])
+				distance := delta.Norm()
+				if distance < epsilon {
+					distance = epsilon
+				}
+				force := delta.Scale((k * k) / (distance * distance))
+				displacements[i] = displacements[i].Add(force)
+				displacements[j] = displacements[j].Sub(force)
+			}
+		}
+
+		// Calculate attractive forces using adjacency list. Edge weights scale the attraction,
+		// so heavily weighted edges pull their endpoints closer together.
+		for i := 0; i < n; i++ {
+			for j := 0; j < nodes.Degree(i); j++ {
+				v := nodes.Neighbor(i, j)
+				if i < v { // Process each edge once
+					delta := positions[v].Sub(positions[i])
+					distance := delta.Norm()
+					if distance < epsilon {
+						distance = epsilon
+					}
+					force := delta.Scale(nodes.Weight(i, j) * distance / k)
+					displacements[i] = displacements[i].Add(force)
+					displacements[v] = displacements[v].Sub(force)
+				}
+			}
+		}
+
+		// Update positions with temperature cooling
+		energy, moved := 0.0, 0.0
+		for i := 0; i < n; i++ {
+			disp := displacements[i]
+			dispNorm := disp.Norm()
+			energy += dispNorm * dispNorm
+			if dispNorm > 0 {
+				disp = disp.Scale(math.Min(dispNorm, t) / dispNorm)
+				newPos := positions[i].Add(disp)
+				newPos.X = clamp(newPos.X, 0, width)
+				newPos.Y = clamp(newPos.Y, 0, height)
+				moved += newPos.Sub(positions[i]).Norm()
+				positions[i] = newPos
+			}
+		}
+
+		result.Iterations, result.Energy = iter+1, energy
+		if opts.converged(moved, n, k) {
+			result.Converged = true
+			bar.Exit()
+			break
+		}
+		cooling.cool(energy)
+	}
+
+	return positions, result
+}
+
+// The ends of every edge u -> v with u < v, listed under both u and v in CSR form. The force
+// layouts pull the ends of each such edge together, so the attraction on node i is the sum over
+// its own list here, and goroutines working on different nodes never write to the same place.
+type springs struct {
+	offsets []int
+	others  []int
+	weights []float64
+}
+
+func newSprings(nodes layout.Adjacency) springs {
+	n := nodes.NumNodes()
+	s := springs{offsets: make([]int, n+1)}
+	for u := 0; u < n; u++ {
+		for j := 0; j < nodes.Degree(u); j++ {
+			if v := nodes.Neighbor(u, j); u < v {
+				s.offsets[u+1]++
+				s.offsets[v+1]++
+			}
+		}
+	}
+	for i := 0; i < n; i++ {
+		s.offsets[i+1] += s.offsets[i]
+	}
+	s.others = make([]int, s.offsets[n])
+	s.weights = make([]float64, s.offsets[n])
+	fill := append([]int(nil), s.offsets[:n]...)
+	for u := 0; u < n; u++ {
+		for j := 0; j < nodes.Degree(u); j++ {
+			if v := nodes.Neighbor(u, j); u < v {
+				w := nodes.Weight(u, j)
+				s.others[fill[u]], s.weights[fill[u]] = v, w
+				s.others[fill[v]], s.weights[fill[v]] = u, w
+				fill[u]++
+				fill[v]++
+			}
+		}
+	}
+	return s
+}
+
+// Attractive force on node i from its edges
+func (s springs) force(positions []layout.Point, i int, k, epsilon float64) layout.Point {
+	total := layout.Point{X: 0, Y: 0}
+	for e := s.offsets[i]; e < s.offsets[i+1]; e++ {
+		delta := positions[s.others[e]].Sub(positions[i])
+		distance := delta.Norm()
+		if distance < epsilon {
+			distance = epsilon
+		}
+		total = total.Add(delta.Scale(s.weights[e] * distance / k))
+	}
+	return total
+}
+
+// Nodes are split into at most this many blocks for repulsion, and never blocks of fewer than
+// repulsionMinBlock nodes
+const (
+	repulsionBlocks   = 64
+	repulsionMinBlock = 16
+)
+
+// Adds the repulsive force between every pair of nodes to disp. Like seq, this visits each pair
+// once and pushes both nodes apart, rather than working out each node's force on its own, which
+// would do every pair twice. The nodes are split into blocks, and pairs of blocks are handed out
+// in rounds by the circle method for round-robin tournaments [2]: each block is in one pair per
+// round, so no two workers write the same node, and the rounds run in a fixed order. The blocks
+// depend only on the number of nodes, so every node adds up its forces in the same order
+// however many workers there are.
+func addRepulsion(pool *workpool.Pool, positions, disp []layout.Point, k, epsilon float64) {
+	n := len(positions)
+	size := max((n+repulsionBlocks-1)/repulsionBlocks, repulsionMinBlock)
+	blocks := (n + size - 1) / size
+	repel := func(a, b int) {
+		a0, a1 := a*size, min((a+1)*size, n)
+		b0, b1 := b*size, min((b+1)*size, n)
+		for i := a0; i < a1; i++ {
+			for j := max(b0, i+1); j < b1; j++ {
+				delta := positions[i].Sub(positions[j])
+				distance := delta.Norm()
+				if distance < epsilon {
+					distance = epsilon
+				}
+				force := delta.Scale((k * k) / (distance * distance))
+				disp[i] = disp[i].Add(force)
+				disp[j] = disp[j].Sub(force)
+			}
+		}
+	}
+	// Pairs within a block
+	pool.Run(blocks, 1, func(start, end int) {
+		for a := start; a < end; a++ {
+			repel(a, a)
+		}
+	})
+	// Pairs between blocks. With an odd number of blocks, the block paired with the extra one
+	// sits the round out.
+	m := blocks + blocks%2
+	for round := 0; round < m-1; round++ {
+		pool.Run(m/2, 1, func(start, end int) {
+			for p := start; p < end; p++ {
+				a, b := round, m-1
+				if p > 0 {
+					a, b = (round+p)%(m-1), (round-p+m-1)%(m-1)
+				}
+				if b < blocks {
+					repel(min(a, b), max(a, b))
+				}
+			}
+		})
+	}
+}
+
+// Moves p by disp, at most t far, and keeps it on the canvas
+func moveNode(p, disp layout.Point, t, width, height float64) layout.Point {
+	dispNorm := disp.Norm()
+	if dispNorm == 0 {
+		return p
+	}
+	newPos := p.Add(disp.Scale(math.Min(dispNorm, t) / dispNorm))
+	newPos.X = clamp(newPos.X, 0, width)
+	newPos.Y = clamp(newPos.Y, 0, height)
+	return newPos
+}
+
+// The force-directed layout with the work handed out to a pool of workers: the repulsion in
+// rounds of blocks (see addRepulsion), then the nodes in chunks of CHUNK_SIZE. Every worker
+// works out the attraction on its own nodes and writes their new positions to a second buffer,
+// so no two workers write to the same place and nothing moves until all the forces are known.
+// Each node adds up its forces in the same order however the nodes are split.
+func forceDirectedLayoutParallel(nodes layout.Adjacency, opts ParallelForceOptions) ([]layout.Point, ForceResult) {
+	n := nodes.NumNodes()
+	iterations, width, height, CHUNK_SIZE := opts.Iterations, opts.Width, opts.Height, opts.ChunkSize
+	positions := assignRandomPositions(nodes, width, height, opts.rand())
+	next, disp := make([]layout.Point, n), make([]layout.Point, n)
+	// Squared force on and distance moved by each node in an iteration
+	energy, moved := make([]float64, n), make([]float64, n)
+	springs := newSprings(nodes)
+	pool := workpool.New(opts.Workers)
+	defer pool.Close()
+
+	k := math.Sqrt((width * height) / float64(n))
+	cooling := opts.cooling()
+	epsilon := 1e-6
+
+	var result ForceResult
+	bar := progressbar.Default(int64(iterations))
+	for iter := 0; iter < iterations; iter++ {
+		bar.Add(1)
+		t := cooling.t
+
+		clear(disp)
+		addRepulsion(pool, positions, disp, k, epsilon)
+		pool.Run(n, CHUNK_SIZE, func(start, end int) {
+			for i := start; i < end; i++ {
+				disp := disp[i].Add(springs.force(positions, i, k, epsilon))
+				next[i] = moveNode(positions[i], disp, t, width, height)
+				energy[i] = disp.X*disp.X + disp.Y*disp.Y
+				moved[i] = next[i].Sub(positions[i]).Norm()
+			}
+		})
+		positions, next = next, positions
+
+		result.Iterations, result.Energy = iter+1, sum(energy)
+		if opts.converged(sum(moved), n, k) {
+			result.Converged = true
+			bar.Exit()
+			break
+		}
+		cooling.cool(result.Energy)
+	}
+
+	return positions, result
+}
+
+func computeRepulsiveForceBarnesHut(p *layout.Point, node *Quadtree, k, theta, epsilon float64) layout.Point {
+	if node == nil || (node.Count == 1 && node.Points[0] == p) {
+		return layout.Point{X: 0, Y: 0}
+	}
+
+	// Compute distance to grid center
+	dx := p.X - node.MidPoint[0]
+	dy := p.Y - node.MidPoint[1]
+	distance := math.Sqrt(dx*dx + dy*dy)
+
+	// Grid width
+	s := node.TopRightCorner[0] - node.BottomLeftCorner[0]
+
+	if s/distance < theta || node.Count == 1 {
+		if distance < epsilon {
+			distance = epsilon
+		}
+		forceMag := (k * k * float64(node.Count)) / (distance * distance)
+		return layout.Point{X: dx, Y: dy}.Scale(forceMag / distance)
+	}
+
+	// A cell at quadtreeMaxDepth can hold several points too close together to split
+	if node.isLeaf() {
+		totalForce := layout.Point{X: 0, Y: 0}
+		for _, q := range node.Points {
+			if q == p {
+				continue
+			}
+			delta := p.Sub(*q)
+			distance := math.Max(delta.Norm(), epsilon)
+			totalForce = totalForce.Add(delta.Scale(k * k / (distance * distance * distance)))
+		}
+		return totalForce
+	}
+
+	// Otherwise recurse into children
+	totalForce := layout.Point{X: 0, Y: 0}
+	children := []*Quadtree{node.BottomLeft, node.BottomRight, node.TopLeft, node.TopRight}
+	for _, child := range children {
+		f := computeRepulsiveForceBarnesHut(p, child, k, theta, epsilon)
+		totalForce = totalForce.Add(f)
+	}
+	return totalForce
+}
+
+// The parallel force-directed layout with repulsion approximated by a Barnes-Hut quadtree
+func forceDirectedQuadtree(nodes layout.Adjacency, opts QuadtreeOptions) ([]layout.Point, ForceResult) {
+	n := nodes.NumNodes()
+	iterations, width, height, CHUNK_SIZE := opts.Iterations, opts.Width, opts.Height, opts.ChunkSize
+	positions := assignRandomPositions(nodes, width, height, opts.rand())
+	next := make([]layout.Point, n)
+	// Squared force on and distance moved by each node in an iteration
+	energy, moved := make([]float64, n), make([]float64, n)
+	springs := newSprings(nodes)
+	pool := workpool.New(opts.Workers)
+	defer pool.Close()
+
+	k := math.Sqrt((width * height) / float64(n))
+	cooling := opts.cooling()
+	epsilon := 1e-6
+	theta := opts.Theta
+
+	points := make([]*layout.Point, n)
+
+	var result ForceResult
+	bar := progressbar.Default(int64(iterations))
+	for iter := 0; iter < iterations; iter++ {
+		bar.Add(1)
+		t := cooling.t
+
+		// The tree is built over this iteration's positions, which stay put until it's done with
+		for i := range positions {
+			points[i] = &positions[i]
+		}
+		root := buildQuadtree(points, [2]float64{0, 0}, [2]float64{width, height}, pool)
+
+		// Each worker moves its own nodes, as in forceDirectedLayoutParallel
+		pool.Run(n, CHUNK_SIZE, func(start, end int) {
+			for i := start; i < end; i++ {
+				disp := computeRepulsiveForceBarnesHut(points[i], root, k, theta, epsilon).
+					Add(springs.force(positions, i, k, epsilon))
+				next[i] = moveNode(positions[i], disp, t, width, height)
+				energy[i] = disp.X*disp.X + disp.Y*disp.Y
+				moved[i] = next[i].Sub(positions[i]).Norm()
+			}
+		})
+		positions, next = next, positions
+
+		result.Iterations, result.Energy = iter+1, sum(energy)
+		if opts.converged(sum(moved), n, k) {
+			result.Converged = true
+			bar.Exit()
+			break
+		}
+		cooling.cool(result.Energy)
+	}
+
+	return positions, result
+}
+
+/* Refs:
+   [1] Y. Hu, "Efficient, High-Quality Force-Directed layout.Graph Drawing", The Mathematica Journal
+       10(1), 2005
+   [2] "Round-robin tournament", Scheduling algorithm,
+       https://en.wikipedia.org/wiki/Round-robin_tournament#Circle_method
+*/
@@ -0,0 +1,232 @@
+// Package builtin registers the layout algorithms that come with ppa-final: the force-directed
+// seq, parallel and quadtree layouts, sugiyama, and given. Programs link them in with
+//
+//	import _ "github.com/clarkep/ppa-final/layout/builtin"
+package builtin
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"time"
+
+	"github.com/clarkep/ppa-final/layout"
+	"github.com/clarkep/ppa-final/layout/sugiyama"
+)
+
+/***** Built-in layouts *****/
+
+// Options of the force-directed layouts
+type ForceOptions struct {
+	// Size of the canvas the nodes are placed on
+	Width, Height float64
+	Iterations    int
+	// Starting temperature, the furthest a node moves in one iteration, as a fraction of Width
+	Temperature float64
+	// How much of the starting temperature is gone by the last iteration, falling linearly; 1
+	// cools all the way down. Unused with a Tolerance, which cools as the energy goes up.
+	Cooling float64
+	// Seed of the starting positions; 0 picks one from the clock and prints it
+	Seed int64
+	// Stop before Iterations once the nodes move less than this many ideal edge lengths per
+	// iteration on average; 0 always runs all the iterations
+	Tolerance float64
+}
+
+var defaultForceOptions = ForceOptions{Width: 800, Height: 600, Iterations: 100, Temperature: 0.1, Cooling: 1}
+
+// The starting temperature and how much it drops per iteration
+func (o ForceOptions) temperature() (float64, float64) {
+	t := o.Width * o.Temperature
+	return t, t * o.Cooling / float64(max(o.Iterations, 1))
+}
+
+// The random source for the starting positions
+func (o ForceOptions) rand() *rand.Rand {
+	seed := o.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+		fmt.Printf("Seed: %d\n", seed)
+	}
+	return rand.New(rand.NewSource(seed))
+}
+
+// Prints how many iterations the layout took and its final energy
+func (r ForceResult) report() {
+	converged := ""
+	if r.Converged {
+		converged = ", converged"
+	}
+	fmt.Printf("Layout iterations: %d%s; energy: %.4g\n", r.Iterations, converged, r.Energy)
+}
+
+func (o *ForceOptions) flags(f layout.Flags) {
+	f.Float64Var(&o.Width, "canvas-width", o.Width, "Width of the canvas force layouts place nodes on")
+	f.Float64Var(&o.Height, "canvas-height", o.Height, "Height of the canvas force layouts place nodes on")
+	f.IntVarP(&o.Iterations, "iter", "i", o.Iterations, "Number of iterations of force layouts")
+	f.Float64Var(&o.Temperature, "temperature", o.Temperature,
+		"Starting temperature of force layouts: the furthest a node moves in an iteration, as a fraction of the canvas width")
+	f.Float64Var(&o.Cooling, "cooling", o.Cooling,
+		"Fraction of the starting temperature force layouts cool down by over their iterations, linearly; unused with --tolerance")
+	f.Int64Var(&o.Seed, "seed", o.Seed,
+		"Random seed for the starting positions of force layouts, so runs can be repeated; 0 picks one and prints it")
+	f.Float64Var(&o.Tolerance, "tolerance", o.Tolerance,
+		"Stop force layouts once nodes move less than this fraction of the ideal edge length per iteration on average, cooling as the energy rises; --iter is then the most iterations run")
+}
+
+func (o ForceOptions) validate() error {
+	switch {
+	case !(o.Width > 0) || !(o.Height > 0):
+		return fmt.Errorf("canvas width and height must be positive")
+	case o.Iterations < 0:
+		return fmt.Errorf("iterations can't be negative")
+	case !(o.Temperature > 0):
+		return fmt.Errorf("temperature must be positive")
+	case !(o.Cooling >= 0 && o.Cooling <= 1):
+		return fmt.Errorf("cooling must be between 0 and 1")
+	case !(o.Tolerance >= 0):
+		return fmt.Errorf("tolerance can't be negative")
+	}
+	return nil
+}
+
+// Options of the parallel force-directed layouts
+type ParallelForceOptions struct {
+	ForceOptions
+	// Goroutines in the worker pool
+	Workers int
+	// Nodes a worker takes at a time; 0 splits the nodes into about 4 chunks per worker
+	ChunkSize int
+}
+
+var defaultParallelForceOptions = ParallelForceOptions{ForceOptions: defaultForceOptions, Workers: runtime.GOMAXPROCS(0)}
+
+func (o *ParallelForceOptions) flags(f layout.Flags) {
+	o.ForceOptions.flags(f)
+	workersFlag(f, &o.Workers)
+	f.IntVar(&o.ChunkSize, "chunk-size", o.ChunkSize,
+		"Nodes a worker of the parallel layouts takes at a time; 0 gives each worker about 4 chunks")
+}
+
+func (o ParallelForceOptions) validate() error {
+	if o.ChunkSize < 0 {
+		return fmt.Errorf("chunk size can't be negative")
+	}
+	if o.Workers < 1 {
+		return fmt.Errorf("workers must be at least 1")
+	}
+	return o.ForceOptions.validate()
+}
+
+func workersFlag(f layout.Flags, p *int) {
+	f.IntVar(p, "workers", *p, "Goroutines the parallel and sugiyama layouts and edge bundling run on, one per CPU by default")
+}
+
+// Options of the Barnes-Hut layout
+type QuadtreeOptions struct {
+	ParallelForceOptions
+	// Cells whose width over their distance is below this are treated as one body
+	Theta float64
+}
+
+var defaultQuadtreeOptions = QuadtreeOptions{ParallelForceOptions: defaultParallelForceOptions, Theta: 0.5}
+
+// Options of the Sugiyama layout
+type SugiyamaOptions struct {
+	// Goroutines assigning levels
+	Workers int
+}
+
+type seqLayout struct{ opts ForceOptions }
+
+func (l *seqLayout) Name() string         { return "seq" }
+func (l *seqLayout) Description() string  { return "Fruchterman-Reingold force-directed layout" }
+func (l *seqLayout) Directed() bool       { return false }
+func (l *seqLayout) Flags(f layout.Flags) { l.opts.flags(f) }
+func (l *seqLayout) Validate() error      { return l.opts.validate() }
+func (l *seqLayout) Layout(in layout.Input) ([]layout.Point, error) {
+	positions, result := forceDirectedLayout(in.Graph, l.opts)
+	result.report()
+	return positions, nil
+}
+
+type parallelLayout struct{ opts ParallelForceOptions }
+
+func (l *parallelLayout) Name() string { return "parallel" }
+func (l *parallelLayout) Description() string {
+	return "force-directed layout with forces computed in parallel"
+}
+func (l *parallelLayout) Directed() bool       { return false }
+func (l *parallelLayout) Flags(f layout.Flags) { l.opts.flags(f) }
+func (l *parallelLayout) Validate() error      { return l.opts.validate() }
+func (l *parallelLayout) Layout(in layout.Input) ([]layout.Point, error) {
+	positions, result := forceDirectedLayoutParallel(in.Graph, l.opts)
+	result.report()
+	return positions, nil
+}
+
+type quadtreeLayout struct{ opts QuadtreeOptions }
+
+func (l *quadtreeLayout) Name() string { return "quadtree" }
+func (l *quadtreeLayout) Description() string {
+	return "parallel force-directed layout with Barnes-Hut approximated repulsion"
+}
+func (l *quadtreeLayout) Directed() bool { return false }
+func (l *quadtreeLayout) Flags(f layout.Flags) {
+	l.opts.ParallelForceOptions.flags(f)
+	f.Float64Var(&l.opts.Theta, "theta", l.opts.Theta, "Barnes-Hut accuracy: lower is more exact and slower")
+}
+func (l *quadtreeLayout) Validate() error {
+	if !(l.opts.Theta >= 0) {
+		return fmt.Errorf("theta can't be negative")
+	}
+	return l.opts.ParallelForceOptions.validate()
+}
+func (l *quadtreeLayout) Layout(in layout.Input) ([]layout.Point, error) {
+	positions, result := forceDirectedQuadtree(in.Graph, l.opts)
+	result.report()
+	return positions, nil
+}
+
+type sugiyamaLayout struct{ opts SugiyamaOptions }
+
+func (l *sugiyamaLayout) Name() string        { return "sugiyama" }
+func (l *sugiyamaLayout) Description() string { return "layered drawing of directed graphs" }
+func (l *sugiyamaLayout) Directed() bool      { return true }
+func (l *sugiyamaLayout) Flags(f layout.Flags) {
+	workersFlag(f, &l.opts.Workers)
+}
+func (l *sugiyamaLayout) Validate() error {
+	if l.opts.Workers < 1 {
+		return fmt.Errorf("workers must be at least 1")
+	}
+	return nil
+}
+func (l *sugiyamaLayout) Layout(in layout.Input) ([]layout.Point, error) {
+	return sugiyama.Layout(in.Graph, l.opts.Workers), nil
+}
+
+// Keeps the coordinates from the input file, to compare with published layouts
+type givenLayout struct{}
+
+func (l givenLayout) Name() string { return "given" }
+func (l givenLayout) Description() string {
+	return "the node coordinates in the input file (Pajek, or node-link JSON with x and y)"
+}
+func (l givenLayout) Directed() bool       { return false }
+func (l givenLayout) Flags(f layout.Flags) {}
+func (l givenLayout) Validate() error      { return nil }
+func (l givenLayout) Layout(in layout.Input) ([]layout.Point, error) {
+	if in.Coords == nil {
+		return nil, fmt.Errorf("the input file has no node coordinates")
+	}
+	return in.Coords, nil
+}
+
+func init() {
+	layout.Register(givenLayout{})
+	layout.Register(&seqLayout{defaultForceOptions})
+	layout.Register(&parallelLayout{defaultParallelForceOptions})
+	layout.Register(&quadtreeLayout{defaultQuadtreeOptions})
+	layout.Register(&sugiyamaLayout{SugiyamaOptions{Workers: runtime.GOMAXPROCS(0)}})
+}
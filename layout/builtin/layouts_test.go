@@ -0,0 +1,252 @@
+package builtin
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/clarkep/ppa-final/internal/workpool"
+	"github.com/clarkep/ppa-final/layout"
+	"github.com/spf13/pflag"
+)
+
+// A layout with its own copy of the force options, to check shared flags
+type testLayout struct {
+	name string
+	opts ForceOptions
+}
+
+func (l *testLayout) Name() string                                   { return l.name }
+func (l *testLayout) Description() string                            { return "test layout" }
+func (l *testLayout) Directed() bool                                 { return false }
+func (l *testLayout) Flags(f layout.Flags)                           { l.opts.flags(f) }
+func (l *testLayout) Validate() error                                { return l.opts.validate() }
+func (l *testLayout) Layout(in layout.Input) ([]layout.Point, error) { return nil, nil }
+
+func TestBuiltinsRegistered(t *testing.T) {
+	for _, name := range []string{"given", "seq", "parallel", "quadtree", "sugiyama"} {
+		if layout.Lookup(name) == nil {
+			t.Errorf("layout %s not registered", name)
+		}
+	}
+	if !layout.Lookup("sugiyama").Directed() || layout.Lookup("seq").Directed() {
+		t.Errorf("only sugiyama should be directed")
+	}
+}
+
+func TestLayoutFlags(t *testing.T) {
+	a := &testLayout{name: "a", opts: defaultForceOptions}
+	b := &testLayout{name: "b", opts: defaultForceOptions}
+	b.opts.Iterations = 7
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	a.Flags(layout.NewFlags(fs))
+	b.Flags(layout.NewFlags(fs))
+
+	// Unset flags keep each algorithm's own default
+	if err := fs.Parse([]string{"--canvas-width", "300", "--cooling=0.5"}); err != nil {
+		t.Fatal(err)
+	}
+	if a.opts.Width != 300 || b.opts.Width != 300 || a.opts.Cooling != 0.5 || b.opts.Cooling != 0.5 {
+		t.Errorf("shared flags not set on both layouts: %+v, %+v", a.opts, b.opts)
+	}
+	if a.opts.Iterations != 100 || b.opts.Iterations != 7 {
+		t.Errorf("unset flag changed the defaults: %d, %d", a.opts.Iterations, b.opts.Iterations)
+	}
+	if err := fs.Parse([]string{"-i", "20"}); err != nil {
+		t.Fatal(err)
+	}
+	if a.opts.Iterations != 20 || b.opts.Iterations != 20 {
+		t.Errorf("-i set iterations to %d, %d, want 20", a.opts.Iterations, b.opts.Iterations)
+	}
+	if err := fs.Parse([]string{"--iter", "x"}); err == nil {
+		t.Errorf("bad flag value accepted")
+	}
+	if err := a.Validate(); err != nil {
+		t.Errorf("valid options rejected: %v", err)
+	}
+	a.opts.Cooling = 2
+	if err := a.Validate(); err == nil {
+		t.Errorf("cooling of 2 accepted")
+	}
+}
+
+func TestForceTemperature(t *testing.T) {
+	// The defaults cool from a tenth of the width to nothing over the iterations
+	opts := defaultForceOptions
+	if t0, rate := opts.temperature(); t0 != 80 || rate != 0.8 {
+		t.Errorf("temperature() = %g, %g, want 80, 0.8", t0, rate)
+	}
+}
+
+// A random graph with n nodes and about n*d/2 edges, listed both ways
+func randomTestGraph(n, d int, seed int64) layout.Graph {
+	rng := rand.New(rand.NewSource(seed))
+	graph := make(layout.Graph, n)
+	for e := 0; e < n*d/2; e++ {
+		u, v := rng.Intn(n), rng.Intn(n)
+		if u != v {
+			graph[u] = append(graph[u], v)
+			graph[v] = append(graph[v], u)
+		}
+	}
+	return graph
+}
+
+func TestLayoutsReproducible(t *testing.T) {
+	graph := randomTestGraph(60, 4, 1)
+	opts := defaultForceOptions
+	opts.Iterations, opts.Seed = 20, 42
+
+	seq, _ := forceDirectedLayout(graph, opts)
+	if again, _ := forceDirectedLayout(graph, opts); !reflect.DeepEqual(seq, again) {
+		t.Errorf("seq layout differs between runs with the same seed")
+	}
+	opts.Seed = 43
+	if other, _ := forceDirectedLayout(graph, opts); reflect.DeepEqual(seq, other) {
+		t.Errorf("seq layout is the same with a different seed")
+	}
+	opts.Seed = 42
+
+	// Neither layout depends on how the nodes are split among the workers
+	splits := []struct{ workers, chunk int }{{1, 0}, {3, 1}, {4, 7}, {2, 1000}}
+	parallel, _ := forceDirectedLayoutParallel(graph, ParallelForceOptions{opts, 1, 0})
+	quadtree, _ := forceDirectedQuadtree(graph, QuadtreeOptions{ParallelForceOptions{opts, 1, 0}, 0.5})
+	for _, split := range splits[1:] {
+		popts := ParallelForceOptions{opts, split.workers, split.chunk}
+		if got, _ := forceDirectedLayoutParallel(graph, popts); !reflect.DeepEqual(got, parallel) {
+			t.Errorf("parallel layout with %d workers, chunk size %d differs", split.workers, split.chunk)
+		}
+		if got, _ := forceDirectedQuadtree(graph, QuadtreeOptions{popts, 0.5}); !reflect.DeepEqual(got, quadtree) {
+			t.Errorf("quadtree layout with %d workers, chunk size %d differs", split.workers, split.chunk)
+		}
+	}
+}
+
+// A graph with a weight on each edge, like main's WeightedGraph
+type weightedTestGraph struct {
+	layout.Graph
+	weights [][]float64
+}
+
+func (g weightedTestGraph) Weight(u, j int) float64 { return g.weights[u][j] }
+
+func TestParallelMatchesSeq(t *testing.T) {
+	// Edges listed one way only, a duplicate edge and a self-loop: the parallel layout still
+	// counts each u -> v with u < v once on both ends, like seq
+	graph := weightedTestGraph{
+		layout.Graph{{1, 1, 2}, {3}, {0, 2}, {}, {0}},
+		[][]float64{{1, 2, 0.5}, {1}, {1, 1}, {}, {3}},
+	}
+	opts := defaultForceOptions
+	opts.Iterations, opts.Seed = 3, 5
+	seq, _ := forceDirectedLayout(graph, opts)
+	parallel, _ := forceDirectedLayoutParallel(graph, ParallelForceOptions{opts, 2, 2})
+	for i := range seq {
+		if d := seq[i].Sub(parallel[i]).Norm(); d > 1e-6 {
+			t.Errorf("node %d at %v in parallel, %v in seq", i, parallel[i], seq[i])
+		}
+	}
+}
+
+// Every pair of nodes repels once, with odd and even numbers of blocks and any number of workers
+func TestAddRepulsion(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	for _, n := range []int{1, 5, 17, 50, 1000} {
+		positions := make([]layout.Point, n)
+		for i := range positions {
+			positions[i] = layout.Point{X: rng.Float64() * 100, Y: rng.Float64() * 100}
+		}
+		want := make([]layout.Point, n)
+		for i := range positions {
+			for j := range positions {
+				if j != i {
+					delta := positions[i].Sub(positions[j])
+					want[i] = want[i].Add(delta.Scale(25 / (delta.X*delta.X + delta.Y*delta.Y)))
+				}
+			}
+		}
+		for _, workers := range []int{1, 3} {
+			pool := workpool.New(workers)
+			got := make([]layout.Point, n)
+			addRepulsion(pool, positions, got, 5, 1e-6)
+			pool.Close()
+			for i := range got {
+				if d := got[i].Sub(want[i]).Norm(); d > 1e-9*(1+want[i].Norm()) {
+					t.Fatalf("n=%d workers=%d: node %d repelled by %v, want %v", n, workers, i, got[i], want[i])
+				}
+			}
+		}
+	}
+}
+
+// The second iteration's forces come from a quadtree of the positions the first one moved the
+// nodes to, not the starting ones, which would give the same forces every iteration
+func TestQuadtreeRebuilt(t *testing.T) {
+	// Without edges, repulsion is the only force
+	graph := make(layout.Graph, 30)
+	opts := defaultForceOptions
+	opts.Iterations, opts.Seed = 1, 7
+	qopts := QuadtreeOptions{ParallelForceOptions{opts, 2, 0}, 0.5}
+	first, r1 := forceDirectedQuadtree(graph, qopts)
+
+	points := make([]*layout.Point, len(first))
+	for i := range first {
+		points[i] = &first[i]
+	}
+	root := constructQuadtreeLayer(points, [2]float64{0, 0}, [2]float64{opts.Width, opts.Height}, nil, 0)
+	k := math.Sqrt(opts.Width * opts.Height / float64(len(graph)))
+	want := 0.0
+	for _, p := range points {
+		f := computeRepulsiveForceBarnesHut(p, root, k, qopts.Theta, 1e-6)
+		want += f.X*f.X + f.Y*f.Y
+	}
+
+	qopts.Iterations = 2
+	_, r2 := forceDirectedQuadtree(graph, qopts)
+	if math.Abs(r2.Energy-want) > 1e-9*want || r2.Energy == r1.Energy {
+		t.Errorf("second iteration energy %g, want %g from the moved positions (first was %g)", r2.Energy, want, r1.Energy)
+	}
+}
+
+func TestConvergence(t *testing.T) {
+	graph := randomTestGraph(40, 3, 2)
+	opts := defaultForceOptions
+	opts.Iterations, opts.Seed, opts.Cooling = 1000, 1, 0.5
+
+	_, full := forceDirectedLayout(graph, opts)
+	if full.Iterations != 1000 || full.Converged {
+		t.Errorf("without a tolerance, ran %d iterations (converged %v), want all 1000", full.Iterations, full.Converged)
+	}
+	opts.Tolerance = 0.01
+	_, seq := forceDirectedLayout(graph, opts)
+	if !seq.Converged || seq.Iterations >= 1000 || seq.Energy <= 0 {
+		t.Errorf("seq layout didn't converge early: %+v", seq)
+	}
+	popts := ParallelForceOptions{opts, 2, 0}
+	_, parallel := forceDirectedLayoutParallel(graph, popts)
+	_, quadtree := forceDirectedQuadtree(graph, QuadtreeOptions{popts, 0.5})
+	for _, r := range []ForceResult{parallel, quadtree} {
+		if !r.Converged || r.Iterations >= 1000 {
+			t.Errorf("parallel layout didn't converge early: %+v", r)
+		}
+	}
+
+	// A stricter tolerance takes longer
+	opts.Tolerance = 0.001
+	if _, strict := forceDirectedLayout(graph, opts); strict.Iterations <= seq.Iterations {
+		t.Errorf("tolerance 0.001 stopped after %d iterations, 0.01 after %d", strict.Iterations, seq.Iterations)
+	}
+}
+
+func TestGivenLayout(t *testing.T) {
+	in := layout.Input{Graph: layout.Graph{{1}, {0}}, Coords: []layout.Point{{X: 1, Y: 2}, {X: 3, Y: 4}}}
+	got, err := layout.Lookup("given").Layout(in)
+	if err != nil || !reflect.DeepEqual(got, in.Coords) {
+		t.Errorf("given layout = %v, %v, want the input coordinates", got, err)
+	}
+	in.Coords = nil
+	if _, err := layout.Lookup("given").Layout(in); err == nil {
+		t.Errorf("given layout of a file without coordinates should fail")
+	}
+}
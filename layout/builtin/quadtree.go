@@ -0,0 +1,166 @@
+package builtin
+
+import (
+	"math"
+
+	"github.com/clarkep/ppa-final/internal/workpool"
+	"github.com/clarkep/ppa-final/layout"
+	"github.com/google/uuid"
+)
+
+// Levels of the quadtree split before the cells are handed to the worker pool, which then
+// builds up to 4^quadtreeSplitDepth subtrees at once
+const quadtreeSplitDepth = 3
+
+// Cells this deep aren't split further, so points at the same spot can't recurse forever
+const quadtreeMaxDepth = 40
+
+type Quadtree struct {
+	BottomLeft, BottomRight, TopLeft, TopRight *Quadtree
+	Points                                     []*layout.Point
+	Parent                                     *Quadtree
+
+	// Number of points in the grid
+	Count int
+
+	// ID of the grid
+	ID string
+
+	// Grid Dimension
+	MidPoint         [2]float64
+	TopRightCorner   [2]float64
+	BottomLeftCorner [2]float64
+}
+
+func newGrid(bottomLeft, topRight [2]float64, id string, points []*layout.Point, parent *Quadtree) *Quadtree {
+	x1, y1 := bottomLeft[0], bottomLeft[1]
+	x2, y2 := topRight[0], topRight[1]
+	return &Quadtree{
+		Points:           points,
+		Parent:           parent,
+		Count:            len(points),
+		ID:               id,
+		TopRightCorner:   topRight,
+		BottomLeftCorner: bottomLeft,
+		MidPoint:         [2]float64{(x1 + x2) / 2, (y1 + y2) / 2},
+		BottomLeft:       nil,
+		BottomRight:      nil,
+		TopLeft:          nil,
+		TopRight:         nil,
+	}
+}
+
+// Splits the points of quadtree into its four quadrants and returns the children that have any
+func (quadtree *Quadtree) split() []*Quadtree {
+	x1, y1 := quadtree.BottomLeftCorner[0], quadtree.BottomLeftCorner[1]
+	x2, y2 := quadtree.TopRightCorner[0], quadtree.TopRightCorner[1]
+	midX, midY := quadtree.MidPoint[0], quadtree.MidPoint[1]
+
+	var bottomLeftPoints, bottomRightPoints, topLeftPoints, topRightPoints []*layout.Point
+	for _, point := range quadtree.Points {
+		if point.X <= midX && point.Y <= midY {
+			bottomLeftPoints = append(bottomLeftPoints, point)
+		} else if point.X > midX && point.Y <= midY {
+			bottomRightPoints = append(bottomRightPoints, point)
+		} else if point.X <= midX && point.Y > midY {
+			topLeftPoints = append(topLeftPoints, point)
+		} else {
+			topRightPoints = append(topRightPoints, point)
+		}
+	}
+
+	var children []*Quadtree
+	child := func(points []*layout.Point, bottomLeft, topRight [2]float64) *Quadtree {
+		if len(points) == 0 {
+			return nil
+		}
+		c := newGrid(bottomLeft, topRight, uuid.New().String(), points, quadtree)
+		children = append(children, c)
+		return c
+	}
+	quadtree.BottomLeft = child(bottomLeftPoints, quadtree.BottomLeftCorner, [2]float64{midX, midY})
+	quadtree.BottomRight = child(bottomRightPoints, [2]float64{midX, y1}, [2]float64{x2, midY})
+	quadtree.TopLeft = child(topLeftPoints, [2]float64{x1, midY}, [2]float64{midX, y2})
+	quadtree.TopRight = child(topRightPoints, [2]float64{midX, midY}, quadtree.TopRightCorner)
+	return children
+}
+
+// Splits quadtree, at the given depth, until every cell holds one point
+func (quadtree *Quadtree) build(depth int) {
+	if quadtree.Count > 1 && depth < quadtreeMaxDepth {
+		for _, child := range quadtree.split() {
+			child.build(depth + 1)
+		}
+	}
+}
+
+func (quadtree *Quadtree) isLeaf() bool {
+	return quadtree.BottomLeft == nil && quadtree.BottomRight == nil && quadtree.TopLeft == nil && quadtree.TopRight == nil
+}
+
+func constructQuadtreeLayer(points []*layout.Point, bottomLeft, topRight [2]float64, parent *Quadtree, depth int) *Quadtree {
+	quadtree := newGrid(bottomLeft, topRight, uuid.New().String(), points, parent)
+	quadtree.build(depth)
+	return quadtree
+}
+
+// Builds the quadtree of points like constructQuadtreeLayer, splitting the top
+// quadtreeSplitDepth levels here and building the subtrees below them on the pool
+func buildQuadtree(points []*layout.Point, bottomLeft, topRight [2]float64, pool *workpool.Pool) *Quadtree {
+	root := newGrid(bottomLeft, topRight, uuid.New().String(), points, nil)
+	var frontier []*Quadtree
+	var expand func(quadtree *Quadtree, depth int)
+	expand = func(quadtree *Quadtree, depth int) {
+		if depth == quadtreeSplitDepth {
+			frontier = append(frontier, quadtree)
+		} else if quadtree.Count > 1 {
+			for _, child := range quadtree.split() {
+				expand(child, depth+1)
+			}
+		}
+	}
+	expand(root, 0)
+	pool.Run(len(frontier), 1, func(start, end int) {
+		for _, quadtree := range frontier[start:end] {
+			quadtree.build(quadtreeSplitDepth)
+		}
+	})
+	return root
+}
+
+func getCommonAncestor(p, q *layout.Point, pointToGrid map[*layout.Point]*Quadtree) *Quadtree {
+	len1 := 0
+	for p_p := pointToGrid[p]; p_p != nil; p_p = p_p.Parent {
+		len1++
+	}
+
+	len2 := 0
+	for p_q := pointToGrid[q]; p_q != nil; p_q = p_q.Parent {
+		len2++
+	}
+
+	larger := p
+	smaller := q
+	if len2 > len1 {
+		larger = q
+		smaller = p
+	}
+	diff := int(math.Abs(float64(len1 - len2)))
+
+	r := pointToGrid[larger]
+	for c := 0; c < diff; c++ {
+		r = r.Parent
+	}
+
+	p_1 := r
+	p_2 := pointToGrid[smaller]
+	for p_1 != nil && p_2 != nil {
+		if p_1.ID == p_2.ID {
+			return p_1
+		}
+		p_1 = p_1.Parent
+		p_2 = p_2.Parent
+	}
+
+	return nil
+}
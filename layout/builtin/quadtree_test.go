@@ -0,0 +1,62 @@
+package builtin
+
+import (
+	"testing"
+
+	"github.com/clarkep/ppa-final/internal/workpool"
+	"github.com/clarkep/ppa-final/layout"
+)
+
+func TestBuildQuadtree(t *testing.T) {
+	// Points on a grid, plus a few at the same spot that can't be split apart
+	var points []*layout.Point
+	for i := 0; i < 400; i++ {
+		points = append(points, &layout.Point{X: float64(i%20) * 5, Y: float64(i/20) * 5})
+	}
+	for i := 0; i < 3; i++ {
+		points = append(points, &layout.Point{X: 0, Y: 0})
+	}
+	pool := workpool.New(4)
+	defer pool.Close()
+	root := buildQuadtree(points, [2]float64{0, 0}, [2]float64{100, 100}, pool)
+	want := constructQuadtreeLayer(points, [2]float64{0, 0}, [2]float64{100, 100}, nil, 0)
+
+	var compare func(a, b *Quadtree) bool
+	compare = func(a, b *Quadtree) bool {
+		if a == nil || b == nil {
+			return a == b
+		}
+		return a.Count == b.Count && a.MidPoint == b.MidPoint &&
+			compare(a.BottomLeft, b.BottomLeft) && compare(a.BottomRight, b.BottomRight) &&
+			compare(a.TopLeft, b.TopLeft) && compare(a.TopRight, b.TopRight)
+	}
+	if !compare(root, want) {
+		t.Errorf("quadtree built on the pool differs from the sequential one")
+	}
+	if root.Count != len(points) {
+		t.Errorf("root holds %d points, want %d", root.Count, len(points))
+	}
+}
+
+// Points closer together than a cell at quadtreeMaxDepth share it, and still push each other
+// apart as if they'd been split
+func TestBarnesHutMaxDepth(t *testing.T) {
+	points := []*layout.Point{{X: 30.3, Y: 30.3}, {X: 30.3 + 1e-12, Y: 30.3}, {X: 30.3, Y: 30.3 - 2e-12}}
+	pool := workpool.New(1)
+	defer pool.Close()
+	root := buildQuadtree(points, [2]float64{0, 0}, [2]float64{100, 100}, pool)
+	for _, p := range points {
+		var want layout.Point
+		for _, q := range points {
+			if q != p {
+				delta := p.Sub(*q)
+				d := delta.Norm()
+				want = want.Add(delta.Scale(4 / (d * d * d)))
+			}
+		}
+		got := computeRepulsiveForceBarnesHut(p, root, 2, 0.5, 1e-15)
+		if d := got.Sub(want).Norm(); d > 1e-9*want.Norm() {
+			t.Errorf("force on %v = %v, want %v", *p, got, want)
+		}
+	}
+}
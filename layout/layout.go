@@ -0,0 +1,89 @@
+// Package layout defines the graphs layout algorithms run on and the registry --algo picks
+// them from. An algorithm implements Algorithm and calls Register from an init function; a
+// program then links it in with a blank import of its package, as main does with
+// layout/builtin.
+package layout
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+type Point struct {
+	X, Y float64
+}
+
+func (p Point) Add(q Point) Point {
+	return Point{X: p.X + q.X, Y: p.Y + q.Y}
+}
+
+func (p Point) Sub(q Point) Point {
+	return Point{X: p.X - q.X, Y: p.Y - q.Y}
+}
+
+func (p Point) Scale(s float64) Point {
+	return Point{X: p.X * s, Y: p.Y * s}
+}
+
+func (p Point) Norm() float64 {
+	return math.Sqrt(p.X*p.X + p.Y*p.Y)
+}
+
+// Graph type using adjacency list
+type Graph [][]int
+
+// Read-only view of a graph's adjacency lists. The layouts take this rather than a Graph so
+// that they can also run on more compact representations, like main's CSRGraph.
+type Adjacency interface {
+	NumNodes() int
+	Degree(u int) int
+	// Target of u's j'th edge
+	Neighbor(u, j int) int
+	// Weight of u's j'th edge, 1 for unweighted graphs
+	Weight(u, j int) float64
+}
+
+func (g Graph) NumNodes() int           { return len(g) }
+func (g Graph) Degree(u int) int        { return len(g[u]) }
+func (g Graph) Neighbor(u, j int) int   { return g[u][j] }
+func (g Graph) Weight(u, j int) float64 { return 1 }
+
+// Prints adjacency list
+func (g Graph) Print() {
+	for i, u := range g {
+		for j, _ := range u {
+			fmt.Printf("%d -> %d\n", i, j)
+		}
+	}
+}
+
+// What a layout is given to place
+type Input struct {
+	// The graph, read as directed if the algorithm is
+	Graph Adjacency
+	// Coordinates of the nodes from the input file, or nil if it has none
+	Coords []Point
+}
+
+/***** Timing *****/
+
+func ScaledTime(ns int64) string {
+	if ns > 1000000000 {
+		return fmt.Sprintf("%.3g s", float64(ns)/1000000000.0)
+	} else if ns > 1000000 {
+		return fmt.Sprintf("%.3g ms", float64(ns)/1000000.0)
+	} else if ns > 1000 {
+		return fmt.Sprintf("%.3g us", float64(ns)/1000.0)
+	} else {
+		return fmt.Sprintf("%.3g ns", float64(ns))
+	}
+}
+
+// Prints how long the phase begun at *phaseStart took and starts the next one. Layouts use it
+// to time their own steps in the same format as the phases of main.
+func EndPhase(phaseName string, phaseStart *time.Time) {
+	phaseEnd := time.Now()
+	fmt.Printf("%s: %s\n", phaseName, ScaledTime(phaseEnd.Sub(*phaseStart).Nanoseconds()))
+	*phaseStart = phaseEnd
+}
@@ -0,0 +1,133 @@
+package layout
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+/***** Layout algorithms *****/
+
+// A layout algorithm that --algo can pick. Algorithms keep their options in their own struct,
+// exposed as flags by Flags, and add themselves to the registry with Register from an init
+// function in their package.
+type Algorithm interface {
+	// What --algo is set to for this algorithm
+	Name() string
+	// One line for --help
+	Description() string
+	// Whether the layout treats edges as directed, and so the graph is read as directed
+	Directed() bool
+	// Defines a flag for each option
+	Flags(flags Flags)
+	// Checks the options once the flags are parsed
+	Validate() error
+	// Places the nodes, returning a position for each
+	Layout(in Input) ([]Point, error)
+}
+
+var registry = make(map[string]Algorithm)
+
+// Makes alg available to --algo. It panics if the name is taken, like database/sql.Register.
+func Register(alg Algorithm) {
+	name := alg.Name()
+	if _, dup := registry[name]; dup {
+		panic("layout: Register called twice for layout " + name)
+	}
+	registry[name] = alg
+}
+
+// The algorithm registered as name, or nil
+func Lookup(name string) Algorithm {
+	return registry[name]
+}
+
+// Names of the registered algorithms, sorted
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Help text listing the registered algorithms
+func Help() string {
+	var b strings.Builder
+	for _, name := range Names() {
+		fmt.Fprintf(&b, "\n  %-10s %s", name, registry[name].Description())
+	}
+	return b.String()
+}
+
+// Defines every registered algorithm's flags on fs
+func DefineFlags(fs *pflag.FlagSet) {
+	for _, name := range Names() {
+		registry[name].Flags(Flags{fs})
+	}
+}
+
+// Where algorithms define their flags. Several algorithms can define the same flag, like
+// --canvas-width, each bound to its own options: setting the flag sets all of them, and each
+// keeps its own default otherwise. The usage text of the first definition is shown.
+type Flags struct {
+	fs *pflag.FlagSet
+}
+
+// Flags defined on fs, for options outside the algorithms that should be set along with
+// theirs, like the --workers of main's edge bundling
+func NewFlags(fs *pflag.FlagSet) Flags {
+	return Flags{fs}
+}
+
+// A flag bound to several algorithms' options
+type sharedFlag struct {
+	values []pflag.Value
+}
+
+func (f *sharedFlag) String() string { return f.values[0].String() }
+func (f *sharedFlag) Type() string   { return f.values[0].Type() }
+func (f *sharedFlag) Set(s string) error {
+	for _, v := range f.values {
+		if err := v.Set(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Adds the flag that define makes on a scratch flag set to f, or binds it to the existing
+// flag of that name
+func (f Flags) add(name, shorthand, usage string, define func(fs *pflag.FlagSet)) {
+	scratch := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	define(scratch)
+	value := scratch.Lookup(name).Value
+	if existing := f.fs.Lookup(name); existing != nil {
+		shared, ok := existing.Value.(*sharedFlag)
+		if !ok || shared.Type() != value.Type() {
+			panic(fmt.Sprintf("layout flag --%s conflicts with another flag", name))
+		}
+		shared.values = append(shared.values, value)
+		return
+	}
+	f.fs.VarP(&sharedFlag{[]pflag.Value{value}}, name, shorthand, usage)
+}
+
+func (f Flags) IntVar(p *int, name string, value int, usage string) {
+	f.IntVarP(p, name, "", value, usage)
+}
+
+func (f Flags) IntVarP(p *int, name, shorthand string, value int, usage string) {
+	f.add(name, shorthand, usage, func(fs *pflag.FlagSet) { fs.IntVar(p, name, value, usage) })
+}
+
+func (f Flags) Int64Var(p *int64, name string, value int64, usage string) {
+	f.add(name, "", usage, func(fs *pflag.FlagSet) { fs.Int64Var(p, name, value, usage) })
+}
+
+func (f Flags) Float64Var(p *float64, name string, value float64, usage string) {
+	f.add(name, "", usage, func(fs *pflag.FlagSet) { fs.Float64Var(p, name, value, usage) })
+}
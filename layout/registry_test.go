@@ -0,0 +1,61 @@
+package layout
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+// An algorithm with one option of its own and one shared with other algorithms
+type testAlgorithm struct {
+	name        string
+	size, steps int
+}
+
+func (a *testAlgorithm) Name() string        { return a.name }
+func (a *testAlgorithm) Description() string { return "test algorithm " + a.name }
+func (a *testAlgorithm) Directed() bool      { return false }
+func (a *testAlgorithm) Flags(f Flags) {
+	f.IntVar(&a.size, "test-size", a.size, "Size")
+	f.IntVar(&a.steps, "test-steps-"+a.name, a.steps, "Steps")
+}
+func (a *testAlgorithm) Validate() error                  { return nil }
+func (a *testAlgorithm) Layout(in Input) ([]Point, error) { return nil, nil }
+
+func TestRegister(t *testing.T) {
+	a := &testAlgorithm{name: "test-a", size: 1, steps: 2}
+	b := &testAlgorithm{name: "test-b", size: 3, steps: 4}
+	Register(b)
+	Register(a)
+	defer delete(registry, a.name)
+	defer delete(registry, b.name)
+
+	if Lookup("test-a") != a || Lookup("test-missing") != nil {
+		t.Errorf("Lookup didn't find the registered algorithm")
+	}
+	names := strings.Join(Names(), " ")
+	if !strings.Contains(names, "test-a test-b") {
+		t.Errorf("Names() = %s, want test-a before test-b", names)
+	}
+	if help := Help(); !strings.Contains(help, "test-a") || !strings.Contains(help, "test algorithm test-b") {
+		t.Errorf("Help() is missing the test algorithms:%s", help)
+	}
+
+	// A flag both define sets both, and each keeps its own default otherwise
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	DefineFlags(fs)
+	if err := fs.Parse([]string{"--test-size", "9", "--test-steps-test-a", "5"}); err != nil {
+		t.Fatal(err)
+	}
+	if a.size != 9 || b.size != 9 || a.steps != 5 || b.steps != 4 {
+		t.Errorf("flags set a to %+v and b to %+v", *a, *b)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("registering a name twice should panic")
+		}
+	}()
+	Register(&testAlgorithm{name: "test-a"})
+}
@@ -0,0 +1,80 @@
+/* Run these tests with:
+	go test -v .
+   They need the examples/dag*.txt graphs; go test -short skips them.
+*/
+package sugiyama
+
+import (
+	"testing"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clarkep/ppa-final/layout"
+)
+
+// Reads an edge list of numbered nodes, one "u v" per line as graph_generator.py writes them
+func readDAG(filename string) (layout.Graph, error) {
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var graph layout.Graph
+	for _, line := range strings.Split(string(src), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		u, err1 := strconv.Atoi(fields[0])
+		v, err2 := strconv.Atoi(fields[1])
+		if err1 != nil || err2 != nil || u < 0 || v < 0 {
+			return nil, fmt.Errorf("bad edge %q", line)
+		}
+		for len(graph) <= max(u, v) {
+			graph = append(graph, nil)
+		}
+		graph[u] = append(graph[u], v)
+	}
+	return graph, nil
+}
+
+func TestSugiyamaSpeedup1 (t *testing.T) {
+	if testing.Short() {
+		t.Skip("speedup table over examples/dag*.txt skipped in short mode")
+	}
+	files := [...]string{
+		"../../examples/dag8.txt",
+		"../../examples/dag40.txt",
+		"../../examples/dag100.txt",
+		"../../examples/dag1000.txt",
+		"../../examples/dag10k.txt",
+		"../../examples/dag100k.txt",
+	}
+
+	procs := [...]int { 1, 2, 4, 8, 12 }
+
+	fmt.Printf("filename              1         2         4         8          12\n")
+	for _, fn := range files {
+		fmt.Printf("%-21s", fn)
+		graph, err := readDAG(fn)
+		if err != nil {
+			t.Fatalf("Error building graph: %v", err)
+		}
+		for _, p := range procs {
+			const trials = 10
+			var time_sum int64 = 0
+			for range trials {
+				start := time.Now()
+				_, _ = assignLevelsPar(graph, p)
+				end := time.Now()
+				time_sum += end.Sub(start).Nanoseconds()
+			}
+			avg_time := time_sum / trials
+
+			fmt.Printf("%10s", layout.ScaledTime(avg_time))
+		}
+		fmt.Printf("\n")
+	}
+}
\ No newline at end of file
@@ -1,4 +1,5 @@
-package main
+// Package sugiyama draws directed graphs in layers, after Sugiyama et al.
+package sugiyama
 
 import (
 	"sort"
@@ -6,13 +7,17 @@ import (
 	"time"
 	//	   "fmt"
 	//		"math/rand"
+
+	"github.com/clarkep/ppa-final/layout"
 )
 
 // Augment a graph with incoming edges. O(n + m)
-func get_incoming_edges(graph Graph) [][]int {
-	res := make([][]int, len(graph))
-	for i, u := range graph {
-		for _, v := range u {
+func get_incoming_edges(graph layout.Adjacency) [][]int {
+	n := graph.NumNodes()
+	res := make([][]int, n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < graph.Degree(i); j++ {
+			v := graph.Neighbor(i, j)
 			res[v] = append(res[v], i)
 		}
 	}
@@ -83,9 +88,9 @@ func (q *BucketQueue) pop(n int) {
 	}
 }
 
-func removeCycles(outgoing Graph) (Graph, [][2]int) {
+func removeCycles(outgoing layout.Adjacency) (layout.Graph, [][2]int) {
 	// Greedy Cycle Removal
-	n := len(outgoing)
+	n := outgoing.NumNodes()
 	incoming := get_incoming_edges(outgoing)
 
 	indeg, outdeg := make([]int, n), make([]int, n)
@@ -100,7 +105,7 @@ func removeCycles(outgoing Graph) (Graph, [][2]int) {
 	for v := range n {
 		alive[v] = true
 		indeg[v] = len(incoming[v])
-		outdeg[v] = len(outgoing[v])
+		outdeg[v] = outgoing.Degree(v)
 		delta[v] = outdeg[v] - indeg[v]
 		q.push(v, delta[v])
 		if outdeg[v] == 0 {
@@ -141,7 +146,8 @@ func removeCycles(outgoing Graph) (Graph, [][2]int) {
 				update(u, -1)
 			}
 		}
-		for _, u := range outgoing[v] {
+		for j := 0; j < outgoing.Degree(v); j++ {
+			u := outgoing.Neighbor(v, j)
 			if alive[u] {
 				indeg[u]--
 				if indeg[u] == 0 {
@@ -195,7 +201,8 @@ func removeCycles(outgoing Graph) (Graph, [][2]int) {
 	res := make([][]int, n)
 	fas := make([][2]int, n)
 	for u := range n {
-		for _, v := range outgoing[u] {
+		for j := 0; j < outgoing.Degree(u); j++ {
+			v := outgoing.Neighbor(u, j)
 			if pos[u] > pos[v] {
 				res[v] = append(res[v], u)
 				fas = append(fas, [2]int{u, v})
@@ -216,7 +223,7 @@ func allTrue(vertexSet []bool) bool {
 	return true
 }
 
-func assignLevels(graph Graph) ([][]int, [][2]int) {
+func assignLevels(graph layout.Graph) ([][]int, [][2]int) {
 	// the "longest path algorithm"
 	out := make([][]int, 1)
 	n := len(graph)
@@ -257,7 +264,7 @@ func assignLevels(graph Graph) ([][]int, [][2]int) {
 	return out[:len(out)-1], levels
 }
 
-func assignLevelsPar(graph Graph, nWorkers int) ([][]int, [][2]int) {
+func assignLevelsPar(graph layout.Graph, nWorkers int) ([][]int, [][2]int) {
 	// the "longest path algorithm", with parallelized search
 	// over nodes
 	out := make([][]int, 1)
@@ -329,7 +336,7 @@ func resetSlice (s *[]int) {
 // then the ordered non-source nodes, and returns an array from levels -> number of sources 
 // we have skipped. levelmap is also modified.
 func barycentricOrder(graph, levels [][]int, levelmap [][2] int) []int {
-    incoming := get_incoming_edges(graph)
+    incoming := get_incoming_edges(layout.Graph(graph))
     nLevels := len(levels)
     nSources := make([]int, nLevels)
     nSources[nLevels-1] = len(levels[nLevels-1])
@@ -432,7 +439,7 @@ func orderSources(graph [][]int, levelmap [][2] int, nSources, level []int, i in
     }
 }
 
-func orderLevels(graph Graph, levels [][]int, levelmap [][2]int) [][]int {
+func orderLevels(graph layout.Graph, levels [][]int, levelmap [][2]int) [][]int {
     nSources := barycentricOrder(graph, levels, levelmap)
     for i := len(levels) - 1; i >= 1; i-- {
         orderSources(graph, levelmap, nSources, levels[i], i)
@@ -440,7 +447,7 @@ func orderLevels(graph Graph, levels [][]int, levelmap [][2]int) [][]int {
 	return levels
 }
 
-func orderLevelsPar(graph Graph, levels [][]int, levelmap [][2]int) [][]int {
+func orderLevelsPar(graph layout.Graph, levels [][]int, levelmap [][2]int) [][]int {
     nSources := barycentricOrder(graph, levels, levelmap)
 
     levels2 := make([][]int, len(levels))
@@ -460,13 +467,13 @@ func orderLevelsPar(graph Graph, levels [][]int, levelmap [][2]int) [][]int {
     return levels2
 }
 
-func assignCoordinates(graph Graph, orders [][]int) []Point {
-	out := make([]Point, len(graph))
+func assignCoordinates(graph layout.Graph, orders [][]int) []layout.Point {
+	out := make([]layout.Point, len(graph))
 	for x, lvl := range orders {
 		n := len(lvl)
 		for i, u := range lvl {
 			// just assign coordinates based on (level, order in level)
-			out[u] = Point{X: float64(len(orders) - x), Y: (100 * float64(n-i)) / float64(n+1)}
+			out[u] = layout.Point{X: float64(len(orders) - x), Y: (100 * float64(n-i)) / float64(n+1)}
 		}
 	}
 	return out
@@ -474,31 +481,42 @@ func assignCoordinates(graph Graph, orders [][]int) []Point {
 
 const subphases = true
 
-func SugiyamaLayout(graph Graph, iterations int) []Point {
+// Level of every node in the layout, with cycles broken the same way
+func Levels(graph layout.Adjacency) []int {
+	acyclic, _ := removeCycles(graph)
+	_, levelmap := assignLevels(acyclic)
+	levels := make([]int, len(levelmap))
+	for i := range levels {
+		levels[i] = levelmap[i][0]
+	}
+	return levels
+}
+
+func Layout(graph layout.Adjacency, workers int) []layout.Point {
 	startTime := time.Now()
 
 	graph2, _ := removeCycles(graph)
 
 	if subphases {
-		endPhase("\tRemove cycles", &startTime)
+		layout.EndPhase("\tRemove cycles", &startTime)
 	}
 
-	levels, levelmap := assignLevelsPar(graph2, iterations)
+	levels, levelmap := assignLevelsPar(graph2, workers)
 
 	if subphases {
-		endPhase("\tAssign levels", &startTime)
+		layout.EndPhase("\tAssign levels", &startTime)
 	}
 
 	orders := orderLevelsPar(graph2, levels, levelmap)
 
 	if subphases {
-		endPhase("\tOrder levels", &startTime)
+		layout.EndPhase("\tOrder levels", &startTime)
 	}
 
 	positions := assignCoordinates(graph2, orders)
 
 	if subphases {
-		endPhase("\tAssign coordinates", &startTime)
+		layout.EndPhase("\tAssign coordinates", &startTime)
 	}
 
 	return positions
@@ -2,18 +2,28 @@ package main
 
 import (
 	"fmt"
+	"math"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/clarkep/ppa-final/layout"
+	_ "github.com/clarkep/ppa-final/layout/builtin"
 	"github.com/spf13/cobra"
 )
 
-func augmentGraph(graph Graph, positions []Point) PosGraph {
-	out := make([]PosNode, len(graph))
-	for i, u := range graph {
+func augmentGraph(data *GraphData, positions []Point) PosGraph {
+	out := make([]PosNode, data.adjacency().NumNodes())
+	for i := range out {
 		out[i].X = float32(positions[i].X)
 		out[i].Y = float32(positions[i].Y)
-		out[i].Edges = u
+		if data.CSR != nil {
+			out[i].Targets = data.CSR.Targets[data.CSR.Offsets[i]:data.CSR.Offsets[i+1]]
+		} else {
+			out[i].Edges = data.Graph[i]
+		}
+		out[i].Name = data.nodeName(i)
+		out[i].Label = data.nodeLabel(i)
 	}
 	return out
 }
@@ -23,115 +33,237 @@ func errexit(message string) {
 	os.Exit(1)
 }
 
-func scaledTime(ns int64) string {
-	if ns > 1000000000 {
-		return fmt.Sprintf("%.3g s", float64(ns) / 1000000000.0)
-	} else if ns > 1000000 {
-		return fmt.Sprintf("%.3g ms", float64(ns) / 1000000.0)
-	} else if ns > 1000 {
-		return fmt.Sprintf("%.3g us", float64(ns) / 1000.0)
-	} else {
-		return fmt.Sprintf("%.3g ns", float64(ns))
-	}
-}
-
-func endPhase(phaseName string, phaseStart *time.Time) {
-	phaseEnd := time.Now()
-	fmt.Printf("%s: %s\n", phaseName, scaledTime(phaseEnd.Sub(*phaseStart).Nanoseconds()))
-	*phaseStart = phaseEnd
-}
-
-func forceDirectedStd(graph Graph, iterations int) []Point {
-	return forceDirectedLayout(graph, iterations, 800., 600.)
-}
-
-func forceDirectedParallelStd(graph Graph, iterations int) []Point {
-	return forceDirectedLayoutParallel(graph, iterations, 800., 600., 1000)
-}
-
-func forceDirectedQuadtreeStd(graph Graph, iterations int) []Point {
-	return forceDirectedQuadtree(graph, iterations, 800., 600., 1000)
-}
-
 func SugiyamaMain() {
 	fmt.Printf("Not implemented yet.\n")
 }
 
 func main() {
 	phaseStart := time.Now()
-	layoutFunc := forceDirectedStd
+	var algo layout.Algorithm
 	directed := false
 
 	var (
 		png        bool
-		iterations int
 		algoType   string
 		filename   string
+		format     string
+		useCSR     bool
+		lenient    bool
+		selfLoops  string
+		outputs    []string
+		pageSize   string
+		tikzWidth  string
+		background string
+		fastRender bool
+		legend     bool
+		styleOpts  StyleOptions
+		bundle     bool
+		bundleOpts = defaultBundleOptions
 	)
+	outputOpts := OutputOptions{Render: defaultRenderOptions, PDF: defaultPDFOptions, TikZ: defaultTikZOptions}
 
 	rootCmd := &cobra.Command{
 		Use:   "ppa-final",
 		Short: "Graph layout visualization tool",
 		Run: func(cmd *cobra.Command, args []string) {
 			// Validate algorithm type
-			validAlgos := map[string]bool{"seq": true, "parallel": true, "sugiyama": true, "quadtree": true}
-			if !validAlgos[algoType] {
-				cobra.CheckErr(fmt.Errorf("invalid algorithm type '%s'. Valid options: seq, parallel, sugiyama", algoType))
+			algo = layout.Lookup(algoType)
+			if algo == nil {
+				cobra.CheckErr(fmt.Errorf("invalid algorithm type '%s'. Valid options: %s", algoType,
+					strings.Join(layout.Names(), ", ")))
 			}
+			cobra.CheckErr(algo.Validate())
+			directed = algo.Directed()
 
-			// Map algorithm type to layout function
-			switch algoType {
-			case "seq":
-				layoutFunc = forceDirectedStd
-			case "parallel":
-				layoutFunc = forceDirectedParallelStd
-			case "sugiyama":
-				layoutFunc = SugiyamaLayout
-				directed = true
-			case "quadtree":
-				layoutFunc = forceDirectedQuadtreeStd
+			if selfLoops != "drop" && selfLoops != "keep" {
+				cobra.CheckErr(fmt.Errorf("invalid self-loop policy '%s'. Valid options: drop, keep", selfLoops))
+			}
+
+			if png {
+				outputs = append(outputs, "output.png")
+			}
+			for _, path := range outputs {
+				cobra.CheckErr(checkOutputPath(path))
+			}
+
+			render := &outputOpts.Render
+			if render.Width < 0 || render.Height < 0 || render.Margin < 0 {
+				cobra.CheckErr(fmt.Errorf("image width, height and margin can't be negative"))
+			}
+			if !(render.Scale > 0) || math.IsInf(render.Scale, 0) {
+				cobra.CheckErr(fmt.Errorf("invalid scale %g: must be positive", render.Scale))
+			}
+			if !(render.EdgeWidth > 0) || math.IsInf(render.EdgeWidth, 0) {
+				cobra.CheckErr(fmt.Errorf("invalid edge width %g: must be positive", render.EdgeWidth))
+			}
+			if !(render.EdgeOpacity >= 0 && render.EdgeOpacity <= 1) {
+				cobra.CheckErr(fmt.Errorf("invalid edge opacity %g: must be between 0 and 1", render.EdgeOpacity))
+			}
+			if !(render.FontSize > 0) || math.IsInf(render.FontSize, 0) {
+				cobra.CheckErr(fmt.Errorf("invalid font size %g: must be positive", render.FontSize))
+			}
+			if !labelPositions[render.LabelPosition] {
+				cobra.CheckErr(fmt.Errorf("invalid label position '%s'. Valid options: right, below, center", render.LabelPosition))
+			}
+			if _, ok := palettes[styleOpts.Palette]; styleOpts.Palette != "" && !ok {
+				cobra.CheckErr(fmt.Errorf("unknown palette '%s'", styleOpts.Palette))
 			}
+			if bundleOpts.Workers < 1 {
+				cobra.CheckErr(fmt.Errorf("workers must be at least 1"))
+			}
+			if bundleOpts.Cycles < 1 || bundleOpts.Iterations < 0 || !(bundleOpts.Step >= 0) {
+				cobra.CheckErr(fmt.Errorf("edge bundling needs at least 1 cycle, and a non-negative iteration count and step"))
+			}
+			if !(bundleOpts.Compatibility >= 0 && bundleOpts.Compatibility <= 1) {
+				cobra.CheckErr(fmt.Errorf("invalid bundling compatibility %g: must be between 0 and 1", bundleOpts.Compatibility))
+			}
+			render.Antialias = !fastRender
+			var err error
+			render.Background, err = parseColor(background)
+			cobra.CheckErr(err)
+			outputOpts.PDF.PageWidth, outputOpts.PDF.PageHeight, err = parsePageSize(pageSize)
+			cobra.CheckErr(err)
+			outputOpts.TikZ.Width, err = parseLength(tikzWidth)
+			cobra.CheckErr(err)
 		},
 	}
 
 	// Boolean flag (default: false)
-	rootCmd.Flags().BoolVarP(&png, "png", "p", false, "Enable PNG output")
-
-	// Required integer flag
-	rootCmd.Flags().IntVarP(&iterations, "iter", "i", 100, "Number of iterations (required)")
-	rootCmd.MarkFlagRequired("iterations")
+	rootCmd.Flags().BoolVarP(&png, "png", "p", false, "Write the drawing to output.png (same as --out output.png)")
 
 	// Enumerated string flag
 	rootCmd.Flags().StringVarP(&algoType, "algo", "a", "",
-		"Algorithm type (seq|parallel|sugiyama|quadtree) (required)")
+		"Layout algorithm (required):"+layout.Help())
 	rootCmd.MarkFlagRequired("algo")
 
+	// Options of the layout algorithms
+	layout.DefineFlags(rootCmd.Flags())
+	// Bundling runs on as many workers as the layout
+	layout.NewFlags(rootCmd.Flags()).IntVar(&bundleOpts.Workers, "workers", bundleOpts.Workers,
+		"Goroutines edge bundling runs on")
+
 	// Enumerated string flag
 	rootCmd.Flags().StringVarP(&filename, "file", "f", "",
-		"Filename (required)")
+		"Filename, or - for stdin; .gz and .zst files are decompressed (required)")
 	rootCmd.MarkFlagRequired("file")
 
+	rootCmd.Flags().StringVar(&format, "format", "",
+		"Input format (edgelist|graphml|dot|mtx|pajek|json), guessed from the file extension if not given")
+
+	rootCmd.Flags().BoolVar(&useCSR, "csr", false,
+		"Store the graph in compact CSR form, for very large inputs")
+
+	rootCmd.Flags().BoolVar(&lenient, "lenient", false,
+		"Accept messy edge lists: skip comments, blank lines and headers, allow comma/tab delimiters and extra columns, merge duplicate edges")
+	rootCmd.Flags().StringVar(&selfLoops, "self-loops", "drop",
+		"What --lenient does with self-loops (drop|keep)")
+
+	rootCmd.Flags().StringArrayVarP(&outputs, "out", "o", nil,
+		"Write the layout to a file, in the format given by its extension (.png|.json|.svg|.pdf|.tex|.dot|.graphml|.gexf); can be repeated")
+	rootCmd.Flags().IntVar(&outputOpts.Render.Width, "width", defaultRenderOptions.Width,
+		"PNG/SVG width in pixels, or 0 to follow the layout's aspect ratio")
+	rootCmd.Flags().IntVar(&outputOpts.Render.Height, "height", defaultRenderOptions.Height,
+		"PNG/SVG height in pixels, or 0 to follow the layout's aspect ratio")
+	rootCmd.Flags().IntVar(&outputOpts.Render.Margin, "margin", 0,
+		"Blank space around the drawing, in pixels")
+	rootCmd.Flags().StringVar(&background, "background", "white",
+		"Background color: white, black, transparent, #rgb, #rrggbb or #rrggbbaa")
+	rootCmd.Flags().Float64Var(&outputOpts.Render.Scale, "scale", 1,
+		"DPI scale factor: multiplies the image size, margin, node size, arrow size and edge width")
+	rootCmd.Flags().Float64Var(&outputOpts.Render.EdgeWidth, "edge-width", defaultRenderOptions.EdgeWidth,
+		"Edge line width in pixels")
+	rootCmd.Flags().Float64Var(&outputOpts.Render.EdgeOpacity, "edge-opacity", defaultRenderOptions.EdgeOpacity,
+		"Edge opacity, from 0 to 1")
+	rootCmd.Flags().BoolVar(&fastRender, "fast", false,
+		"Draw the PNG and window with plain 1 pixel lines instead of anti-aliasing; faster, but ignores --edge-width and --edge-opacity")
+	rootCmd.Flags().BoolVar(&outputOpts.Render.Labels, "labels", false,
+		"Label the nodes of the PNG and window with their label attribute, or their name")
+	rootCmd.Flags().Float64Var(&outputOpts.Render.FontSize, "font-size", defaultRenderOptions.FontSize,
+		"Label font size in pixels")
+	rootCmd.Flags().StringVar(&outputOpts.Render.LabelPosition, "label-position", defaultRenderOptions.LabelPosition,
+		"Where labels go relative to their node (right|below|center)")
+	rootCmd.Flags().Float64Var(&outputOpts.Render.LabelMinSpacing, "label-min-spacing", defaultRenderOptions.LabelMinSpacing,
+		"Leave labels out unless there are at least this many pixels per node on average; 0 always draws them")
+	rootCmd.Flags().StringVar(&styleOpts.ColorBy, "color-by", "",
+		"Color nodes by degree, level, component, or the named node attribute")
+	rootCmd.Flags().StringVar(&styleOpts.SizeBy, "size-by", "",
+		"Size nodes by degree or the named numeric node attribute")
+	rootCmd.Flags().StringVar(&styleOpts.Palette, "palette", "",
+		"Colors for --color-by (tab10|set2|dark2|paired|viridis|plasma|cividis); tab10 for categories and viridis for numbers by default")
+	rootCmd.Flags().BoolVar(&legend, "legend", true,
+		"Draw a legend for --color-by in the PNG and window")
+	rootCmd.Flags().BoolVar(&bundle, "bundle", false,
+		"Bundle edges with force-directed edge bundling; drawn by the PNG, window, SVG, PDF and TikZ outputs")
+	rootCmd.Flags().IntVar(&bundleOpts.Cycles, "bundle-cycles", defaultBundleOptions.Cycles,
+		"Edge bundling subdivision cycles; each doubles the points per edge")
+	rootCmd.Flags().IntVar(&bundleOpts.Iterations, "bundle-iter", defaultBundleOptions.Iterations,
+		"Edge bundling iterations in the first cycle")
+	rootCmd.Flags().Float64Var(&bundleOpts.Step, "bundle-step", defaultBundleOptions.Step,
+		"Edge bundling step size in the first cycle, in a drawing 1000 units across")
+	rootCmd.Flags().Float64Var(&bundleOpts.Compatibility, "bundle-compat", defaultBundleOptions.Compatibility,
+		"Least compatibility, from 0 to 1, for edges to bundle together")
+	rootCmd.Flags().StringVar(&pageSize, "page-size", "a4",
+		"PDF page size: a3|a4|a5|letter|legal, with an optional -landscape, or WIDTHxHEIGHT in pt, mm, cm or in")
+	rootCmd.Flags().StringVar(&tikzWidth, "tikz-width", "12cm",
+		"Width of TikZ (.tex) output, in pt, mm, cm or in")
+
 	cobra.CheckErr(rootCmd.Execute())
 
 	startTime := time.Now()
 
-	graph, err := buildGraphFromFile(filename, directed)
+	var data *GraphData
+	var err error
+	opts := EdgeListOptions{Lenient: lenient, SelfLoops: selfLoops}
+	if useCSR {
+		data, err = buildCSRFromFile(filename, format, directed, opts)
+	} else {
+		data, err = buildGraphFromFile(filename, format, directed, opts)
+	}
 	if err != nil {
 		errexit(fmt.Sprintf("Error building graph: %v\n", err))
 	}
-	endPhase("Build graph", &phaseStart)
+	if data.Cleanup != nil {
+		data.Cleanup.Print()
+	}
+	directed = data.Directed
+	layout.EndPhase("Build graph", &phaseStart)
+	if bundle {
+		if err := checkBundleSize(data.adjacency(), directed); err != nil {
+			errexit(fmt.Sprintf("Error bundling edges: %v\n", err))
+		}
+	}
 
-	positions := layoutFunc(graph, iterations)
-	endPhase("Compute layout", &phaseStart)
+	positions, err := algo.Layout(layout.Input{Graph: data.adjacency(), Coords: data.Coords})
+	if err != nil {
+		errexit(fmt.Sprintf("Error computing layout: %v\n", err))
+	}
+	layout.EndPhase("Compute layout", &phaseStart)
 
-	outGraph := augmentGraph(graph, positions)
+	outGraph := augmentGraph(data, positions)
+	if bundle {
+		bundleEdges(outGraph, directed, bundleOpts)
+		layout.EndPhase("Bundle edges", &phaseStart)
+	}
+	key, err := applyStyle(outGraph, data, styleOpts)
+	if err != nil {
+		errexit(fmt.Sprintf("Error styling nodes: %v\n", err))
+	}
+	if legend {
+		outputOpts.Render.Legend = key
+	}
 
-	if !png {
-		RenderGUI(outGraph, directed)
-	} else {
-		RenderPNG(outGraph, directed)
-		endPhase("Create PNG", &phaseStart)
+	for _, path := range outputs {
+		if err := writeOutput(path, outGraph, data, directed, outputOpts); err != nil {
+			errexit(fmt.Sprintf("Error writing output: %v\n", err))
+		}
+	}
+	if len(outputs) > 0 {
+		layout.EndPhase("Write output", &phaseStart)
+	}
+
+	if len(outputs) == 0 {
+		RenderGUI(outGraph, directed, outputOpts.Render)
 	}
 
-	fmt.Printf("Total time: %s\n", scaledTime(time.Since(startTime).Nanoseconds()))
+	fmt.Printf("Total time: %s\n", layout.ScaledTime(time.Since(startTime).Nanoseconds()))
 }
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/***** Matrix Market input *****/
+
+// Reads a sparse Matrix Market file (as used by the SuiteSparse collection) as a graph: a
+// nonzero at (i, j) is an edge from node i to node j. General matrices give directed graphs;
+// symmetric, skew-symmetric and hermitian ones give undirected graphs with each stored entry
+// used once. Diagonal entries are dropped. Entry magnitudes become edge weights unless the
+// matrix is a pattern matrix. Nodes are named by their 1-based row/column number.
+func readMatrixMarket(r io.Reader) (*GraphData, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNum := 0
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("invalid Matrix Market file: empty")
+	}
+	lineNum++
+	header := strings.Fields(strings.ToLower(scanner.Text()))
+	if len(header) != 5 || header[0] != "%%matrixmarket" || header[1] != "matrix" {
+		return nil, fmt.Errorf("invalid Matrix Market header: %s", scanner.Text())
+	}
+	if header[2] != "coordinate" {
+		return nil, fmt.Errorf("unsupported Matrix Market format '%s': only sparse coordinate matrices are supported", header[2])
+	}
+	field, symmetry := header[3], header[4]
+	switch field {
+	case "real", "double", "integer", "pattern", "complex":
+	default:
+		return nil, fmt.Errorf("unsupported Matrix Market field '%s'", field)
+	}
+	switch symmetry {
+	case "general", "symmetric", "skew-symmetric", "hermitian":
+	default:
+		return nil, fmt.Errorf("unsupported Matrix Market symmetry '%s'", symmetry)
+	}
+
+	// Skip comments up to the size line
+	var rows, cols, nnz int
+	for {
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("invalid Matrix Market file: missing size line")
+		}
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '%' {
+			continue
+		}
+		parts := strings.Fields(line)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("line %d: invalid Matrix Market size line: %s", lineNum, line)
+		}
+		var err1, err2, err3 error
+		rows, err1 = strconv.Atoi(parts[0])
+		cols, err2 = strconv.Atoi(parts[1])
+		nnz, err3 = strconv.Atoi(parts[2])
+		if err1 != nil || err2 != nil || err3 != nil || rows < 0 || cols < 0 || nnz < 0 {
+			return nil, fmt.Errorf("line %d: invalid Matrix Market size line: %s", lineNum, line)
+		}
+		break
+	}
+
+	n := max(rows, cols)
+	out := &GraphData{
+		Graph:    make(Graph, n),
+		Directed: symmetry == "general",
+		NodeIDs:  make([]string, n),
+	}
+	for i := range out.NodeIDs {
+		out.NodeIDs[i] = strconv.Itoa(i + 1)
+	}
+	var weights EdgeWeights
+	if field != "pattern" {
+		weights = make(EdgeWeights, n)
+	}
+
+	entries := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '%' {
+			continue
+		}
+		parts := strings.Fields(line)
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("line %d: invalid Matrix Market entry: %s", lineNum, line)
+		}
+		i, err1 := strconv.Atoi(parts[0])
+		j, err2 := strconv.Atoi(parts[1])
+		if err1 != nil || err2 != nil || i < 1 || i > rows || j < 1 || j > cols {
+			return nil, fmt.Errorf("line %d: invalid Matrix Market entry: %s", lineNum, line)
+		}
+		w := 1.0
+		if field != "pattern" {
+			if len(parts) < 3 {
+				return nil, fmt.Errorf("line %d: Matrix Market entry without a value: %s", lineNum, line)
+			}
+			x, err := strconv.ParseFloat(parts[2], 64)
+			if field == "complex" && err == nil && len(parts) >= 4 {
+				var im float64
+				im, err = strconv.ParseFloat(parts[3], 64)
+				x = math.Hypot(x, im)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid Matrix Market value: %s", lineNum, line)
+			}
+			w = math.Abs(x)
+		}
+		entries++
+
+		u, v := i-1, j-1
+		if u == v {
+			continue
+		}
+		out.Graph[u] = append(out.Graph[u], v)
+		if weights != nil {
+			weights[u] = append(weights[u], w)
+		}
+		if !out.Directed {
+			out.Graph[v] = append(out.Graph[v], u)
+			if weights != nil {
+				weights[v] = append(weights[v], w)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if entries != nnz {
+		return nil, fmt.Errorf("invalid Matrix Market file: size line promises %d entries, found %d", nnz, entries)
+	}
+
+	out.Weights = weights
+	return out, nil
+}
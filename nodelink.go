@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+/***** d3 node-link JSON input and output *****/
+
+type nodeLinkDoc struct {
+	Directed *bool                        `json:"directed"`
+	Nodes    []map[string]json.RawMessage `json:"nodes"`
+	Links    []map[string]json.RawMessage `json:"links"`
+	// networkx writes "edges" instead of "links" since 3.4
+	Edges []map[string]json.RawMessage `json:"edges"`
+}
+
+// Turns a JSON value into an attribute string: strings are unquoted, anything else is kept as
+// JSON text
+func jsonAttr(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return string(raw)
+}
+
+// Reads a d3/networkx node-link graph. Links refer to nodes by their "id", or by their index
+// in the node list if the nodes have no ids. Every other node and link field is kept as an
+// attribute, and numeric "x" and "y" on every node (as in our own output) become Coords.
+// "directed" comes from the file if it says, and from the argument otherwise.
+func readNodeLinkJSON(r io.Reader, directed bool) (*GraphData, error) {
+	var doc nodeLinkDoc
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid node-link JSON: %v", err)
+	}
+	links := doc.Links
+	if links == nil {
+		links = doc.Edges
+	}
+	if doc.Directed != nil {
+		directed = *doc.Directed
+	}
+
+	n := len(doc.Nodes)
+	out := &GraphData{
+		Graph:    make(Graph, n),
+		Directed: directed,
+		NodeIDs:  make([]string, n),
+		Attrs: AttrTable{
+			Nodes: make([]Attrs, n),
+			Edges: make(map[[2]int]Attrs),
+		},
+	}
+	idsToIndices := make(map[string]int)
+	hasCoords := n > 0
+	coords := make([]Point, n)
+	for i, node := range doc.Nodes {
+		out.NodeIDs[i] = strconv.Itoa(i)
+		if id, ok := node["id"]; ok {
+			out.NodeIDs[i] = jsonAttr(id)
+			idsToIndices[out.NodeIDs[i]] = i
+		}
+		out.Attrs.Nodes[i] = make(Attrs)
+		for k, v := range node {
+			if k != "id" {
+				out.Attrs.Nodes[i][k] = jsonAttr(v)
+			}
+		}
+		x, errX := strconv.ParseFloat(out.Attrs.Nodes[i]["x"], 64)
+		y, errY := strconv.ParseFloat(out.Attrs.Nodes[i]["y"], 64)
+		coords[i] = Point{X: x, Y: y}
+		hasCoords = hasCoords && errX == nil && errY == nil
+	}
+	if hasCoords {
+		out.Coords = coords
+	}
+
+	endpoint := func(raw json.RawMessage) (int, error) {
+		if raw == nil {
+			return 0, fmt.Errorf("invalid node-link JSON: link without source or target")
+		}
+		key := jsonAttr(raw)
+		if len(idsToIndices) > 0 {
+			if i, ok := idsToIndices[key]; ok {
+				return i, nil
+			}
+		} else if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < n {
+			return i, nil
+		}
+		return 0, fmt.Errorf("invalid node-link JSON: link to unknown node %s", key)
+	}
+	for _, link := range links {
+		u, err := endpoint(link["source"])
+		if err != nil {
+			return nil, err
+		}
+		v, err := endpoint(link["target"])
+		if err != nil {
+			return nil, err
+		}
+		out.Graph[u] = append(out.Graph[u], v)
+		if !directed {
+			out.Graph[v] = append(out.Graph[v], u)
+		}
+		attrs := make(Attrs)
+		for k, val := range link {
+			if k != "source" && k != "target" {
+				attrs[k] = jsonAttr(val)
+			}
+		}
+		out.Attrs.Edges[[2]int{u, v}] = attrs
+	}
+	return out, nil
+}
+
+// Turns an attribute string back into a JSON value. Attributes don't remember their types, so
+// anything that looks like a number, boolean or JSON object/array is written as one.
+func attrJSON(s string) json.RawMessage {
+	if _, err := strconv.ParseFloat(s, 64); err == nil || s == "true" || s == "false" {
+		if json.Valid([]byte(s)) {
+			return json.RawMessage(s)
+		}
+	}
+	if len(s) > 0 && (s[0] == '{' || s[0] == '[') && json.Valid([]byte(s)) {
+		return json.RawMessage(s)
+	}
+	b, _ := json.Marshal(s)
+	return b
+}
+
+// Writes a laid out graph as d3 node-link JSON, with each node's final position in "x" and
+// "y" and the attributes from the input file carried along. Links refer to node ids.
+func writeNodeLinkJSON(w io.Writer, graph PosGraph, data *GraphData, directed bool) error {
+	type doc struct {
+		Directed bool                         `json:"directed"`
+		Nodes    []map[string]json.RawMessage `json:"nodes"`
+		Links    []map[string]json.RawMessage `json:"links"`
+	}
+	out := doc{Directed: directed, Nodes: make([]map[string]json.RawMessage, len(graph))}
+	ids := exportIDs(graph)
+	for i, node := range graph {
+		fields := make(map[string]json.RawMessage)
+		if i < len(data.Attrs.Nodes) {
+			for k, v := range data.Attrs.Nodes[i] {
+				fields[k] = attrJSON(v)
+			}
+		}
+		fields["id"], _ = json.Marshal(ids[i])
+		fields["x"], _ = json.Marshal(node.X)
+		fields["y"], _ = json.Marshal(node.Y)
+		out.Nodes[i] = fields
+	}
+	out.Links = make([]map[string]json.RawMessage, 0)
+	forEachEdge(graph, directed, func(u, v, j int) {
+		fields := make(map[string]json.RawMessage)
+		for k, val := range data.exportEdgeAttrs(u, v, j) {
+			fields[k] = attrJSON(val)
+		}
+		fields["source"], _ = json.Marshal(ids[u])
+		fields["target"], _ = json.Marshal(ids[v])
+		out.Links = append(out.Links, fields)
+	})
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
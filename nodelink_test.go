@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadNodeLinkJSON(t *testing.T) {
+	src := `{
+  "directed": false,
+  "nodes": [{"id": "a", "group": 1}, {"id": "b", "group": 2}, {"id": 3}],
+  "links": [{"source": "a", "target": "b", "weight": 2}, {"source": "b", "target": 3}]
+}`
+	data, err := readNodeLinkJSON(strings.NewReader(src), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := data.weightsFromAttrs(); err != nil {
+		t.Fatal(err)
+	}
+	if data.Directed {
+		t.Errorf("expected the file's \"directed\" to win")
+	}
+	if want := []string{"a", "b", "3"}; !reflect.DeepEqual(data.NodeIDs, want) {
+		t.Errorf("node ids = %v, want %v", data.NodeIDs, want)
+	}
+	if want := (Graph{{1}, {0, 2}, {1}}); !reflect.DeepEqual(data.Graph, want) {
+		t.Errorf("graph = %v, want %v", data.Graph, want)
+	}
+	if want := (EdgeWeights{{2}, {2, 1}, {1}}); !reflect.DeepEqual(data.Weights, want) {
+		t.Errorf("weights = %v, want %v", data.Weights, want)
+	}
+	if got := data.Attrs.Nodes[1]["group"]; got != "2" {
+		t.Errorf("group of b = %q, want \"2\"", got)
+	}
+
+	// d3 style: links by index, no ids
+	src = `{"nodes": [{"name": "x"}, {"name": "y"}], "links": [{"source": 1, "target": 0}]}`
+	data, err = readNodeLinkJSON(strings.NewReader(src), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Graph{nil, {0}}); !reflect.DeepEqual(data.Graph, want) {
+		t.Errorf("graph = %v, want %v", data.Graph, want)
+	}
+
+	src = `{"nodes": [{"id": "a"}], "links": [{"source": "a", "target": "z"}]}`
+	if _, err := readNodeLinkJSON(strings.NewReader(src), false); err == nil {
+		t.Errorf("expected an error for a link to an unknown node")
+	}
+}
+
+func TestWriteNodeLinkJSON(t *testing.T) {
+	src := `{"nodes": [{"id": "a", "group": 1, "kind": "svc"}, {"id": "b"}],
+  "links": [{"source": "a", "target": "b", "weight": 2}, {"source": "b", "target": "b"}]}`
+	data, err := readNodeLinkJSON(strings.NewReader(src), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph := augmentGraph(data, []Point{{X: 1, Y: 2}, {X: 3, Y: 4}})
+
+	var buf bytes.Buffer
+	if err := writeNodeLinkJSON(&buf, graph, data, false); err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		Directed bool                     `json:"directed"`
+		Nodes    []map[string]interface{} `json:"nodes"`
+		Links    []map[string]interface{} `json:"links"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"id": "a", "x": 1.0, "y": 2.0, "group": 1.0, "kind": "svc"}
+	if !reflect.DeepEqual(out.Nodes[0], want) {
+		t.Errorf("node a = %v, want %v", out.Nodes[0], want)
+	}
+	// each undirected edge, self-loops included, is written once
+	wantLinks := []map[string]interface{}{
+		{"source": "a", "target": "b", "weight": 2.0},
+		{"source": "b", "target": "b"},
+	}
+	if !reflect.DeepEqual(out.Links, wantLinks) {
+		t.Errorf("links = %v, want %v", out.Links, wantLinks)
+	}
+
+	// the output reads back as the same graph
+	back, err := readNodeLinkJSON(&buf, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back.Graph, data.Graph) {
+		t.Errorf("round trip graph = %v, want %v", back.Graph, data.Graph)
+	}
+	if want := []Point{{X: 1, Y: 2}, {X: 3, Y: 4}}; !reflect.DeepEqual(back.Coords, want) {
+		t.Errorf("round trip coords = %v, want %v", back.Coords, want)
+	}
+}
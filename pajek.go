@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/***** Pajek .net input *****/
+
+// Splits a Pajek line into fields, keeping double-quoted labels together. Also returns which
+// fields were quoted.
+func pajekFields(line string) ([]string, []bool, error) {
+	var fields []string
+	var quoted []bool
+	for i := 0; i < len(line); {
+		switch {
+		case line[i] == ' ' || line[i] == '\t' || line[i] == '\r':
+			i++
+		case line[i] == '"':
+			end := strings.IndexByte(line[i+1:], '"')
+			if end < 0 {
+				return nil, nil, fmt.Errorf("unterminated label")
+			}
+			fields = append(fields, line[i+1:i+1+end])
+			quoted = append(quoted, true)
+			i += end + 2
+		default:
+			start := i
+			for i < len(line) && line[i] != ' ' && line[i] != '\t' && line[i] != '\r' {
+				i++
+			}
+			fields = append(fields, line[start:i])
+			quoted = append(quoted, false)
+		}
+	}
+	return fields, quoted, nil
+}
+
+// Reads a Pajek network: a "*Vertices n" section with optional labels and coordinates,
+// followed by any number of "*Arcs"/"*Edges" (one edge per line, with an optional weight) and
+// "*Arcslist"/"*Edgeslist" (a node followed by its neighbors) sections. The graph is directed
+// if it has any arcs; edges are then added in both directions. Vertices without a label are
+// named by their number.
+func readPajek(r io.Reader) (*GraphData, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	lineNum := 0
+
+	out := &GraphData{}
+	var weights EdgeWeights
+	weighted := false
+	hasCoords := false
+	// edges are collected first, since we only know whether the graph is directed at the end
+	type pajekEdge struct {
+		u, v     int
+		w        float64
+		directed bool
+	}
+	var edges []pajekEdge
+	section := ""
+
+	node := func(field string) (int, error) {
+		i, err := strconv.Atoi(field)
+		if err != nil || i < 1 || i > len(out.Graph) {
+			return 0, fmt.Errorf("invalid vertex %s", field)
+		}
+		return i - 1, nil
+	}
+
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '%' {
+			continue
+		}
+		if line[0] == '*' {
+			parts := strings.Fields(line)
+			section = strings.ToLower(parts[0])
+			switch section {
+			case "*vertices":
+				if len(parts) < 2 {
+					return nil, fmt.Errorf("line %d: *Vertices without a count", lineNum)
+				}
+				n, err := strconv.Atoi(parts[1])
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("line %d: invalid vertex count %s", lineNum, parts[1])
+				}
+				out.Graph = make(Graph, n)
+				out.NodeIDs = make([]string, n)
+				out.Coords = make([]Point, n)
+				out.Attrs.Nodes = make([]Attrs, n)
+				for i := range out.NodeIDs {
+					out.NodeIDs[i] = strconv.Itoa(i + 1)
+					out.Attrs.Nodes[i] = make(Attrs)
+				}
+			case "*arcs", "*edges", "*arcslist", "*edgeslist":
+				if out.Graph == nil {
+					return nil, fmt.Errorf("line %d: %s before *Vertices", lineNum, parts[0])
+				}
+			case "*network":
+			default:
+				return nil, fmt.Errorf("line %d: unsupported Pajek section %s", lineNum, parts[0])
+			}
+			continue
+		}
+
+		fields, quoted, err := pajekFields(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNum, err)
+		}
+		switch section {
+		case "*vertices":
+			i, err := node(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNum, err)
+			}
+			rest := fields[1:]
+			if len(rest) > 0 {
+				if _, err := strconv.ParseFloat(rest[0], 64); err != nil || quoted[1] {
+					out.NodeIDs[i] = rest[0]
+					out.Attrs.Nodes[i]["label"] = rest[0]
+					rest = rest[1:]
+				}
+			}
+			if len(rest) >= 2 {
+				x, errX := strconv.ParseFloat(rest[0], 64)
+				y, errY := strconv.ParseFloat(rest[1], 64)
+				if errX == nil && errY == nil {
+					out.Coords[i] = Point{X: x, Y: y}
+					out.Attrs.Nodes[i]["x"] = rest[0]
+					out.Attrs.Nodes[i]["y"] = rest[1]
+					hasCoords = true
+				}
+			}
+		case "*arcs", "*edges":
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("line %d: invalid edge: %s", lineNum, line)
+			}
+			u, err1 := node(fields[0])
+			v, err2 := node(fields[1])
+			if err1 != nil || err2 != nil {
+				return nil, fmt.Errorf("line %d: invalid edge: %s", lineNum, line)
+			}
+			w := 1.0
+			if len(fields) >= 3 {
+				w, err = strconv.ParseFloat(fields[2], 64)
+				if err != nil || math.IsNaN(w) || math.IsInf(w, 0) {
+					return nil, fmt.Errorf("line %d: invalid weight %s", lineNum, fields[2])
+				}
+				// Pajek allows signed weights; only the strength matters for layout
+				w = math.Abs(w)
+				weighted = true
+			}
+			edges = append(edges, pajekEdge{u, v, w, section == "*arcs"})
+		case "*arcslist", "*edgeslist":
+			u, err := node(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNum, err)
+			}
+			for _, f := range fields[1:] {
+				v, err := node(f)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %v", lineNum, err)
+				}
+				edges = append(edges, pajekEdge{u, v, 1, section == "*arcslist"})
+			}
+		default:
+			return nil, fmt.Errorf("line %d: data outside of a section: %s", lineNum, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if out.Graph == nil {
+		return nil, fmt.Errorf("invalid Pajek file: no *Vertices section")
+	}
+
+	for _, e := range edges {
+		if e.directed {
+			out.Directed = true
+			break
+		}
+	}
+	if weighted {
+		weights = make(EdgeWeights, len(out.Graph))
+	}
+	for _, e := range edges {
+		out.Graph[e.u] = append(out.Graph[e.u], e.v)
+		if weighted {
+			weights[e.u] = append(weights[e.u], e.w)
+		}
+		if !out.Directed || !e.directed {
+			out.Graph[e.v] = append(out.Graph[e.v], e.u)
+			if weighted {
+				weights[e.v] = append(weights[e.v], e.w)
+			}
+		}
+	}
+	out.Weights = weights
+	if !hasCoords {
+		out.Coords = nil
+	}
+	return out, nil
+}
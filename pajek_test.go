@@ -0,0 +1,70 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadPajek(t *testing.T) {
+	src := `*Network test
+% comment
+*Vertices 4
+1 "svc auth" 0.1 0.2 0.5
+2 b 0.3 0.4
+3 "3"
+4
+*Arcs
+1 2 2.5
+*Edges
+2 3
+*Arcslist
+4 1 3
+`
+	data, err := readPajek(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !data.Directed {
+		t.Errorf("expected a network with arcs to be directed")
+	}
+	if want := []string{"svc auth", "b", "3", "4"}; !reflect.DeepEqual(data.NodeIDs, want) {
+		t.Errorf("node ids = %v, want %v", data.NodeIDs, want)
+	}
+	if want := (Graph{{1}, {2}, {1}, {0, 2}}); !reflect.DeepEqual(data.Graph, want) {
+		t.Errorf("graph = %v, want %v", data.Graph, want)
+	}
+	if want := (EdgeWeights{{2.5}, {1}, {1}, {1, 1}}); !reflect.DeepEqual(data.Weights, want) {
+		t.Errorf("weights = %v, want %v", data.Weights, want)
+	}
+	if want := (Point{X: 0.3, Y: 0.4}); data.Coords[1] != want {
+		t.Errorf("coords of b = %v, want %v", data.Coords[1], want)
+	}
+}
+
+func TestReadMatrixMarket(t *testing.T) {
+	src := `%%MatrixMarket matrix coordinate real symmetric
+% a 3x3 matrix
+3 3 4
+1 1 4.0
+2 1 -1.5
+3 2 2
+3 3 1
+`
+	data, err := readMatrixMarket(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Directed {
+		t.Errorf("expected a symmetric matrix to give an undirected graph")
+	}
+	if want := (Graph{{1}, {0, 2}, {1}}); !reflect.DeepEqual(data.Graph, want) {
+		t.Errorf("graph = %v, want %v", data.Graph, want)
+	}
+	if want := (EdgeWeights{{1.5}, {1.5, 2}, {2}}); !reflect.DeepEqual(data.Weights, want) {
+		t.Errorf("weights = %v, want %v", data.Weights, want)
+	}
+	if _, err := readMatrixMarket(strings.NewReader("%%MatrixMarket matrix array real general\n2 2\n")); err == nil {
+		t.Errorf("expected an error for a dense array matrix")
+	}
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+/***** PDF output *****/
+
+type PDFOptions struct {
+	// Page size in points (1/72 inch)
+	PageWidth, PageHeight float64
+	// Draw node names next to the nodes
+	Labels bool
+	// Label size in points
+	FontSize float64
+}
+
+var defaultPDFOptions = PDFOptions{PageWidth: 595.28, PageHeight: 841.89, Labels: true, FontSize: 6}
+
+// Named page sizes in points, portrait
+var pageSizes = map[string][2]float64{
+	"a3":     {841.89, 1190.55},
+	"a4":     {595.28, 841.89},
+	"a5":     {419.53, 595.28},
+	"letter": {612, 792},
+	"legal":  {612, 1008},
+}
+
+// Parses a page size: a name from pageSizes, optionally followed by "-landscape", or
+// WIDTHxHEIGHT with an optional unit of pt (the default), mm, cm or in, e.g. "160x120mm" or
+// "8.5inx11in".
+func parsePageSize(s string) (float64, float64, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	name, landscape := strings.CutSuffix(s, "-landscape")
+	if size, ok := pageSizes[name]; ok {
+		if landscape {
+			return size[1], size[0], nil
+		}
+		return size[0], size[1], nil
+	}
+
+	// a unit on the height alone applies to both
+	ws, hs, ok := strings.Cut(s, "x")
+	const number = "0123456789.+-eE"
+	if strings.TrimLeft(ws, number) == "" {
+		ws += strings.TrimLeft(hs, number)
+	}
+	w, errW := parseLength(ws)
+	h, errH := parseLength(hs)
+	if !ok || errW != nil || errH != nil {
+		return 0, 0, fmt.Errorf("invalid page size '%s': use a4, a3, a5, letter or legal (with an optional -landscape), or WIDTHxHEIGHT in pt, mm, cm or in", s)
+	}
+	return w, h, nil
+}
+
+// Space left around the drawing, in points
+const pdfMargin = 18
+
+// Width of the canvas the graph is laid out on before it is scaled to the page. Using the
+// same size as RenderPNG keeps nodes, lines and arrows in the same proportions.
+const pdfCanvasWidth = 2000
+
+// Builds a PDF file out of numbered objects
+type pdfWriter struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+// Starts the next object and returns its number. Objects must be written in number order.
+func (p *pdfWriter) begin() int {
+	if p.buf.Len() == 0 {
+		// The binary comment tells transfer tools the file isn't text
+		p.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	}
+	p.offsets = append(p.offsets, p.buf.Len())
+	n := len(p.offsets)
+	fmt.Fprintf(&p.buf, "%d 0 obj\n", n)
+	return n
+}
+
+func (p *pdfWriter) object(body string) int {
+	n := p.begin()
+	p.buf.WriteString(body)
+	p.buf.WriteString("\nendobj\n")
+	return n
+}
+
+// Writes a Flate compressed stream object. extra goes into the stream dictionary.
+func (p *pdfWriter) stream(data []byte, extra string) int {
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	zw.Write(data)
+	zw.Close()
+	n := p.begin()
+	fmt.Fprintf(&p.buf, "<< /Length %d /Filter /FlateDecode%s >>\nstream\n", z.Len(), extra)
+	p.buf.Write(z.Bytes())
+	p.buf.WriteString("\nendstream\nendobj\n")
+	return n
+}
+
+func (p *pdfWriter) finish(w io.Writer, root int) error {
+	xref := p.buf.Len()
+	fmt.Fprintf(&p.buf, "xref\n0 %d\n0000000000 65535 f \n", len(p.offsets)+1)
+	for _, off := range p.offsets {
+		fmt.Fprintf(&p.buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&p.buf, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(p.offsets)+1, root, xref)
+	_, err := w.Write(p.buf.Bytes())
+	return err
+}
+
+// Glyphs used by the labels, so the font dictionaries only list those
+type pdfFont struct {
+	font   *sfnt.Font
+	buf    sfnt.Buffer
+	upem   fixed.Int26_6
+	used   map[sfnt.GlyphIndex]rune
+	widths map[sfnt.GlyphIndex]float64
+}
+
+func newPDFFont() (*pdfFont, error) {
+	f, err := sfnt.Parse(goregular.TTF)
+	if err != nil {
+		return nil, err
+	}
+	return &pdfFont{
+		font:   f,
+		upem:   fixed.Int26_6(f.UnitsPerEm()) << 6,
+		used:   make(map[sfnt.GlyphIndex]rune),
+		widths: make(map[sfnt.GlyphIndex]float64),
+	}, nil
+}
+
+// Converts font units at a size of one em to the 1/1000 em units PDF font dictionaries use
+func (f *pdfFont) thousandths(x fixed.Int26_6) float64 {
+	return float64(x) * 1000 / float64(f.upem)
+}
+
+// Encodes s as a hex string of glyph ids, and returns its width in ems. Characters the font
+// doesn't have come out as its missing glyph.
+func (f *pdfFont) encode(s string) (string, float64) {
+	var b strings.Builder
+	b.WriteByte('<')
+	width := 0.0
+	for _, r := range s {
+		gid, err := f.font.GlyphIndex(&f.buf, r)
+		if err != nil {
+			gid = 0
+		}
+		if _, ok := f.widths[gid]; !ok {
+			adv, err := f.font.GlyphAdvance(&f.buf, gid, f.upem, font.HintingNone)
+			if err != nil {
+				adv = 0
+			}
+			f.widths[gid] = f.thousandths(adv)
+			f.used[gid] = r
+		}
+		width += f.widths[gid] / 1000
+		fmt.Fprintf(&b, "%04X", uint16(gid))
+	}
+	b.WriteByte('>')
+	return b.String(), width
+}
+
+// Writes the font objects and returns the number of the Type0 font dictionary. The whole
+// TrueType file is embedded, and glyph ids are used directly as character codes.
+func (f *pdfFont) write(p *pdfWriter) (int, error) {
+	metrics, err := f.font.Metrics(&f.buf, f.upem, font.HintingNone)
+	if err != nil {
+		return 0, err
+	}
+	bounds, err := f.font.Bounds(&f.buf, f.upem, font.HintingNone)
+	if err != nil {
+		return 0, err
+	}
+	gids := make([]int, 0, len(f.used))
+	for gid := range f.used {
+		gids = append(gids, int(gid))
+	}
+	sort.Ints(gids)
+	// The missing glyph doesn't stand for any one character
+	mapped := gids
+	if len(mapped) > 0 && mapped[0] == 0 {
+		mapped = mapped[1:]
+	}
+
+	fontFile := p.stream(goregular.TTF, fmt.Sprintf(" /Length1 %d", len(goregular.TTF)))
+	// sfnt bounds have y pointing down
+	descriptor := p.object(fmt.Sprintf("<< /Type /FontDescriptor /FontName /GoRegular /Flags 32 "+
+		"/FontBBox [%.0f %.0f %.0f %.0f] /ItalicAngle 0 /Ascent %.0f /Descent %.0f /CapHeight %.0f "+
+		"/StemV 80 /FontFile2 %d 0 R >>",
+		f.thousandths(bounds.Min.X), -f.thousandths(bounds.Max.Y), f.thousandths(bounds.Max.X), -f.thousandths(bounds.Min.Y),
+		f.thousandths(metrics.Ascent), -f.thousandths(metrics.Descent), f.thousandths(metrics.CapHeight), fontFile))
+
+	var widths strings.Builder
+	for _, gid := range gids {
+		fmt.Fprintf(&widths, "%d [%.0f] ", gid, f.widths[sfnt.GlyphIndex(gid)])
+	}
+	cidFont := p.object(fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /GoRegular "+
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> "+
+		"/FontDescriptor %d 0 R /CIDToGIDMap /Identity /W [%s] >>", descriptor, widths.String()))
+
+	// Lets viewers map the glyphs back to text for search and copy
+	var cmap strings.Builder
+	cmap.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	for start := 0; start < len(mapped); start += 100 {
+		block := mapped[start:min(start+100, len(mapped))]
+		fmt.Fprintf(&cmap, "%d beginbfchar\n", len(block))
+		for _, gid := range block {
+			fmt.Fprintf(&cmap, "<%04X> <", gid)
+			for _, unit := range utf16Units(f.used[sfnt.GlyphIndex(gid)]) {
+				fmt.Fprintf(&cmap, "%04X", unit)
+			}
+			cmap.WriteString(">\n")
+		}
+		cmap.WriteString("endbfchar\n")
+	}
+	cmap.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	toUnicode := p.stream([]byte(cmap.String()), "")
+
+	return p.object(fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /GoRegular /Encoding /Identity-H "+
+		"/DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>", cidFont, toUnicode)), nil
+}
+
+func utf16Units(r rune) []uint16 {
+	if r < 0x10000 {
+		return []uint16{uint16(r)}
+	}
+	r -= 0x10000
+	return []uint16{uint16(0xd800 + r>>10), uint16(0xdc00 + r&0x3ff)}
+}
+
+func pdfColor(c color.RGBA) string {
+	return fmt.Sprintf("%.3f %.3f %.3f", float64(c.R)/255, float64(c.G)/255, float64(c.B)/255)
+}
+
+// Writes the graph as a one page vector PDF, styled like drawGraph: edges, arrowheads on
+// directed edges, then filled nodes, with node names underneath if opts.Labels is set. The
+// drawing is laid out on a canvas with the page's aspect ratio and RenderPNG's width, using
+// the same mapping as drawGraph, and scaled to fit the page inside a small margin.
+func writePDF(w io.Writer, graph PosGraph, directed bool, opts PDFOptions) error {
+	innerW, innerH := opts.PageWidth-2*pdfMargin, opts.PageHeight-2*pdfMargin
+	if innerW <= 0 || innerH <= 0 {
+		return fmt.Errorf("page size %gx%gpt is too small", opts.PageWidth, opts.PageHeight)
+	}
+	scale := innerW / pdfCanvasWidth
+	canvasW, canvasH := pdfCanvasWidth, int(innerH/scale)
+	boundary := getBoundary(graph)
+
+	var content bytes.Buffer
+	// Flip the y axis so canvas coordinates can be used directly
+	fmt.Fprintf(&content, "q\n%.5f 0 0 %.5f %.3f %.3f cm\n", scale, -scale, float64(pdfMargin), opts.PageHeight-pdfMargin)
+	fmt.Fprintf(&content, "1 J 1 j 1 w\n")
+
+	fmt.Fprintf(&content, "%s RG\n", pdfColor(edgeColor))
+	forEachEdge(graph, directed, func(u, v, j int) {
+		for k, p := range graph.edgePath(u, j) {
+			x, y := translateCoords(float32(p.X), float32(p.Y), boundary, canvasW, canvasH)
+			if k == 0 {
+				fmt.Fprintf(&content, "%d %d m", x, y)
+			} else {
+				fmt.Fprintf(&content, " %d %d l", x, y)
+			}
+		}
+		fmt.Fprintf(&content, " S\n")
+	})
+	if directed {
+		fmt.Fprintf(&content, "%s RG\n", pdfColor(arrowColor))
+		forEachEdge(graph, directed, func(u, v, j int) {
+			// The arrowhead lines up with the last stretch of a bundled edge
+			path := graph.edgePath(u, j)
+			from := path[len(path)-2]
+			x1, y1 := translateCoords(float32(from.X), float32(from.Y), boundary, canvasW, canvasH)
+			x2, y2 := translateCoords(graph[v].X, graph[v].Y, boundary, canvasW, canvasH)
+			dx, dy := float64(x2-x1), float64(y2-y1)
+			r := math.Sqrt(dx*dx + dy*dy)
+			if r == 0 {
+				return
+			}
+			// Same arrowhead as drawDirectedLine
+			R := 20.0
+			rx, ry := dx/r, dy/r
+			nodeR := float64(nodeRadius) * graph[v].sizeFactor()
+			tipX := float64(x2) - nodeR*rx
+			tipY := float64(y2) - nodeR*ry
+			leftX := tipX + arrowLeftRotMatrix.xx*R*rx + arrowLeftRotMatrix.xy*R*ry
+			leftY := tipY + arrowLeftRotMatrix.yx*R*rx + arrowLeftRotMatrix.yy*R*ry
+			rightX := tipX + arrowRightRotMatrix.xx*R*rx + arrowRightRotMatrix.xy*R*ry
+			rightY := tipY + arrowRightRotMatrix.yx*R*rx + arrowRightRotMatrix.yy*R*ry
+			fmt.Fprintf(&content, "%.2f %.2f m %.2f %.2f l %.2f %.2f l S\n", leftX, leftY, tipX, tipY, rightX, rightY)
+		})
+	}
+
+	// Circles as four Bezier arcs
+	const kappa = 0.5523
+	fill := nodeColor
+	fmt.Fprintf(&content, "%s rg\n", pdfColor(fill))
+	for _, node := range graph {
+		if node.fill() != fill {
+			fill = node.fill()
+			fmt.Fprintf(&content, "%s rg\n", pdfColor(fill))
+		}
+		r := float64(nodeRadius) * node.sizeFactor()
+		c := kappa * r
+		xi, yi := translateCoords(node.X, node.Y, boundary, canvasW, canvasH)
+		x, y := float64(xi), float64(yi)
+		fmt.Fprintf(&content, "%.2f %.2f m ", x+r, y)
+		fmt.Fprintf(&content, "%.2f %.2f %.2f %.2f %.2f %.2f c ", x+r, y+c, x+c, y+r, x, y+r)
+		fmt.Fprintf(&content, "%.2f %.2f %.2f %.2f %.2f %.2f c ", x-c, y+r, x-r, y+c, x-r, y)
+		fmt.Fprintf(&content, "%.2f %.2f %.2f %.2f %.2f %.2f c ", x-r, y-c, x-c, y-r, x, y-r)
+		fmt.Fprintf(&content, "%.2f %.2f %.2f %.2f %.2f %.2f c f\n", x+c, y-r, x+r, y-c, x+r, y)
+	}
+
+	var labelFont *pdfFont
+	if opts.Labels && len(graph) > 0 {
+		var err error
+		labelFont, err = newPDFFont()
+		if err != nil {
+			return err
+		}
+		size := opts.FontSize / scale
+		fmt.Fprintf(&content, "0 0 0 rg\nBT\n/F1 %.3f Tf\n", size)
+		for _, node := range graph {
+			xi, yi := translateCoords(node.X, node.Y, boundary, canvasW, canvasH)
+			text, width := labelFont.encode(node.Name)
+			// Centered below the node; the text matrix flips y back so glyphs are upright
+			x := float64(xi) - width*size/2
+			y := float64(yi) + float64(nodeRadius)*node.sizeFactor() + size
+			fmt.Fprintf(&content, "1 0 0 -1 %.2f %.2f Tm %s Tj\n", x, y, text)
+		}
+		fmt.Fprintf(&content, "ET\n")
+	}
+	fmt.Fprintf(&content, "Q\n")
+
+	// The font and content go first, so the page can refer back to them
+	var p pdfWriter
+	resources := "<< >>"
+	if labelFont != nil {
+		fontObj, err := labelFont.write(&p)
+		if err != nil {
+			return err
+		}
+		resources = fmt.Sprintf("<< /Font << /F1 %d 0 R >> >>", fontObj)
+	}
+	contentObj := p.stream(content.Bytes(), "")
+	page, pages := contentObj+1, contentObj+2
+	p.object(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f] /Contents %d 0 R /Resources %s >>",
+		pages, opts.PageWidth, opts.PageHeight, contentObj, resources))
+	p.object(fmt.Sprintf("<< /Type /Pages /Kids [%d 0 R] /Count 1 >>", page))
+	catalog := p.object(fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pages))
+	return p.finish(w, catalog)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParsePageSize(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		w, h float64
+	}{
+		{"a4", 595.28, 841.89},
+		{"Letter-landscape", 792, 612},
+		{"400x300", 400, 300},
+		{"2x1in", 144, 72},
+		{"254x127mm", 720, 360},
+		{"254mmx127mm", 720, 360},
+		{"8.5inx11in", 612, 792},
+		{"1inx72", 72, 72},
+	} {
+		w, h, err := parsePageSize(c.in)
+		if err != nil || fmt.Sprintf("%.2f %.2f", w, h) != fmt.Sprintf("%.2f %.2f", c.w, c.h) {
+			t.Errorf("parsePageSize(%q) = %v, %v, %v, want %v, %v", c.in, w, h, err, c.w, c.h)
+		}
+	}
+	for _, bad := range []string{"", "a0", "100", "0x100", "100xmm", "-5x5"} {
+		if _, _, err := parsePageSize(bad); err == nil {
+			t.Errorf("parsePageSize(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+// Decompresses every stream in a PDF file, in object order
+func pdfStreams(t *testing.T, pdf []byte) []string {
+	var out []string
+	re := regexp.MustCompile(`(?s)/Length (\d+)[^>]*>>\nstream\n`)
+	for _, m := range re.FindAllSubmatchIndex(pdf, -1) {
+		n, _ := strconv.Atoi(string(pdf[m[2]:m[3]]))
+		zr, err := zlib.NewReader(bytes.NewReader(pdf[m[1] : m[1]+n]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, string(data))
+	}
+	return out
+}
+
+func TestWritePDF(t *testing.T) {
+	graph := PosGraph{
+		{X: 0, Y: 0, Edges: []int{1}, Name: "ab"},
+		{X: 1, Y: 1, Edges: []int{0}, Name: "ä"},
+	}
+	var buf bytes.Buffer
+	opts := defaultPDFOptions
+	opts.PageWidth, opts.PageHeight = 400, 300
+	if err := writePDF(&buf, graph, true, opts); err != nil {
+		t.Fatal(err)
+	}
+	pdf := buf.Bytes()
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")) {
+		t.Errorf("missing PDF header")
+	}
+
+	// The cross-reference table points at each object
+	xref := bytes.LastIndex(pdf, []byte("\nxref\n")) + 1
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(pdf)
+	if startxref == nil || string(startxref[1]) != strconv.Itoa(xref) {
+		t.Fatalf("bad startxref")
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(pdf[xref:], -1)
+	for i, e := range entries {
+		off, _ := strconv.Atoi(string(e[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(pdf[off:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q", i+1, pdf[off:off+10])
+		}
+	}
+	if !bytes.Contains(pdf, []byte("/MediaBox [0 0 400.00 300.00]")) {
+		t.Errorf("missing page size")
+	}
+
+	// font file, ToUnicode map, page content
+	streams := pdfStreams(t, pdf)
+	if len(streams) != 3 {
+		t.Fatalf("got %d streams, want 3", len(streams))
+	}
+	for _, want := range []string{"<0061>", "<0062>", "<00E4>"} {
+		if !strings.Contains(streams[1], want) {
+			t.Errorf("ToUnicode map is missing %s", want)
+		}
+	}
+	content := streams[2]
+	if n := strings.Count(content, " l S\n"); n != 4 {
+		t.Errorf("content has %d strokes, want 2 edges and 2 arrowheads", n)
+	}
+	if n := strings.Count(content, " c f\n"); n != 2 {
+		t.Errorf("content has %d filled nodes, want 2", n)
+	}
+	if n := strings.Count(content, " Tj\n"); n != 2 {
+		t.Errorf("content has %d labels, want 2", n)
+	}
+
+	opts.Labels = false
+	buf.Reset()
+	if err := writePDF(&buf, graph, false, opts); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("/Font")) {
+		t.Errorf("unlabeled PDF embeds a font")
+	}
+}
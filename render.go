@@ -6,8 +6,12 @@ import (
 	"image/color"
 	"image/draw"
 	"image/png"
+	"io"
 	"math"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 
 	"log"
 
@@ -23,15 +27,90 @@ type Boundary struct {
 type PosNode struct {
 	X, Y  float32
 	Edges []int
+	// The edges of CSR input, a slice of the CSR's Targets rather than a copy; used when Edges
+	// is nil
+	Targets []int32
+	// Node name from the input file
+	Name string
+	// Text drawn by the node when labels are on; the name is used if it's empty
+	Label string
+	// Fill color, or nodeColor if it's zero
+	Color color.RGBA
+	// Radius as a multiple of the usual one, or 1 if it's zero
+	Size float32
+	// Points the edge to Edges[j] passes through on its way, in layout coordinates, when
+	// edges are bundled; nil for a straight edge
+	Bends [][]Point
+}
+
+// Number of edges out of n
+func (n *PosNode) degree() int {
+	if n.Edges == nil {
+		return len(n.Targets)
+	}
+	return len(n.Edges)
+}
+
+// The node at the other end of n's j-th edge
+func (n *PosNode) neighbor(j int) int {
+	if n.Edges == nil {
+		return int(n.Targets[j])
+	}
+	return n.Edges[j]
+}
+
+// Points of the edge from node u to graph[u].neighbor(j), bends included
+func (graph PosGraph) edgePath(u, j int) []Point {
+	v := graph[u].neighbor(j)
+	path := []Point{{X: float64(graph[u].X), Y: float64(graph[u].Y)}}
+	if j < len(graph[u].Bends) {
+		path = append(path, graph[u].Bends[j]...)
+	}
+	return append(path, Point{X: float64(graph[v].X), Y: float64(graph[v].Y)})
+}
+
+func (n PosNode) fill() color.RGBA {
+	if n.Color == (color.RGBA{}) {
+		return nodeColor
+	}
+	return n.Color
+}
+
+func (n PosNode) sizeFactor() float64 {
+	if n.Size == 0 {
+		return 1
+	}
+	return float64(n.Size)
 }
 
 type PosGraph []PosNode
 
+// Calls fn once for every edge u -> v of the graph, where v is graph[u].neighbor(j). Undirected
+// edges are stored in both directions, so only the u <= v copy is passed on, and a self-loop
+// every other time it appears.
+func forEachEdge(graph PosGraph, directed bool, fn func(u, v, j int)) {
+	for u := range graph {
+		loops := 0
+		for j := 0; j < graph[u].degree(); j++ {
+			v := graph[u].neighbor(j)
+			switch {
+			case directed || u < v:
+				fn(u, v, j)
+			case u == v:
+				if loops%2 == 0 {
+					fn(u, v, j)
+				}
+				loops++
+			}
+		}
+	}
+}
+
 var testgraph = PosGraph{
-	{X: 0, Y: 0, Edges: []int{1, 2, 3}},
-	{X: 1, Y: 0, Edges: []int{0, 3}},
-	{X: 0, Y: 1, Edges: []int{0, 3}},
-	{X: 1, Y: 1, Edges: []int{1, 2}},
+	{X: 0, Y: 0, Edges: []int{1, 2, 3}, Name: "0"},
+	{X: 1, Y: 0, Edges: []int{0, 3}, Name: "1"},
+	{X: 0, Y: 1, Edges: []int{0, 3}, Name: "2"},
+	{X: 1, Y: 1, Edges: []int{1, 2}, Name: "3"},
 }
 
 var edgeColor = color.RGBA{0, 0, 0, 255}
@@ -39,18 +118,166 @@ var arrowColor = color.RGBA{32, 32, 255, 255}
 var nodeColor = color.RGBA{0, 0, 255, 255}
 var nodeRadius = 12
 
+// How the PNG and SVG drawings and the GUI are sized and styled
+type RenderOptions struct {
+	// Image size in pixels. If one of them is 0, it follows from the layout's aspect ratio.
+	// The GUI uses the window size instead.
+	Width, Height int
+	// Blank space around the drawing, in pixels
+	Margin int
+	// Fully transparent if its alpha is 0
+	Background color.RGBA
+	// Multiplies the image size, margin, node size, arrow size and edge width, for high DPI
+	// output
+	Scale float64
+	// Draw with the anti-aliased rasterizer. Otherwise lines are 1 pixel Bresenham lines,
+	// which is faster, and EdgeWidth and EdgeOpacity are ignored.
+	Antialias bool
+	// Edge line width in pixels
+	EdgeWidth float64
+	// Edge opacity in [0, 1]; lower values keep dense graphs readable
+	EdgeOpacity float64
+	// Draw each node's label, or its name if it has none
+	Labels bool
+	// Label font size in pixels
+	FontSize float64
+	// Where labels go: "right" of the node, "below" it, or "center"ed on it
+	LabelPosition string
+	// Labels are left out when the drawing has fewer than this many pixels per node on
+	// average, so big graphs don't turn into a wall of text
+	LabelMinSpacing float64
+	// Color key drawn in the top right corner; empty for none
+	Legend []LegendEntry
+}
+
+var defaultRenderOptions = RenderOptions{
+	Width: 2000, Height: 2000, Background: color.RGBA{255, 255, 255, 255}, Scale: 1,
+	Antialias: true, EdgeWidth: 1, EdgeOpacity: 1,
+	FontSize: 12, LabelPosition: "right", LabelMinSpacing: 40,
+}
+
+// Radius of a node of the usual size, in pixels
+func (o RenderOptions) radius() int {
+	return max(1, round64(float64(nodeRadius)*o.Scale))
+}
+
+// Radius of node n in pixels, going by its Size
+func (o RenderOptions) radiusOf(n PosNode) int {
+	return max(1, round64(float64(nodeRadius)*o.Scale*n.sizeFactor()))
+}
+
+// Distance from the image edges to the outermost node centers, leaving room for the biggest
+// node
+func (o RenderOptions) inset(graph PosGraph) int {
+	r := o.radius()
+	for _, node := range graph {
+		r = max(r, o.radiusOf(node))
+	}
+	return round64(float64(o.Margin)*o.Scale) + r
+}
+
+// Image size for drawing graph: the scaled Width and Height, with a 0 filled in from the
+// layout's aspect ratio
+func (o RenderOptions) imageSize(graph PosGraph) (int, int) {
+	w, h := round64(float64(o.Width)*o.Scale), round64(float64(o.Height)*o.Scale)
+	if w == 0 && h == 0 {
+		w = round64(float64(defaultRenderOptions.Width) * o.Scale)
+	}
+	inset := o.inset(graph)
+	boundary := getBoundary(graph)
+	aspect := float64(boundary.Top-boundary.Bottom) / float64(boundary.Right-boundary.Left)
+	if h == 0 {
+		h = 2*inset + round64(float64(max(w-2*inset, 1))*aspect)
+	} else if w == 0 {
+		w = 2*inset + round64(float64(max(h-2*inset, 1))/aspect)
+	}
+	return max(w, 2*inset+1), max(h, 2*inset+1)
+}
+
+// Parses a background color: a name (white, black, transparent) or #rgb, #rrggbb or
+// #rrggbbaa
+func parseColor(s string) (color.RGBA, error) {
+	switch strings.ToLower(s) {
+	case "white":
+		return color.RGBA{255, 255, 255, 255}, nil
+	case "black":
+		return color.RGBA{0, 0, 0, 255}, nil
+	case "transparent", "none":
+		return color.RGBA{}, nil
+	}
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if !strings.HasPrefix(s, "#") || len(hex) != 8 || err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid color '%s': use white, black, transparent, #rgb, #rrggbb or #rrggbbaa", s)
+	}
+	c := color.NRGBA{uint8(v >> 24), uint8(v >> 16), uint8(v >> 8), uint8(v)}
+	// image.RGBA holds premultiplied colors
+	return color.RGBAModel.Convert(c).(color.RGBA), nil
+}
+
 /***** Rendering subroutines *****/
 
 /* Bresenham's line algorithm, translated from [1] */
+// The steps s in [0, length] of a Bresenham line that land inside img's bounds, for a line that
+// takes length steps along its major axis a, from a1 in direction aDir, while moving delta along
+// its minor axis b, from b1 in direction bDir. lo > hi if the line misses. Tiles only walk the
+// part of a line that crosses them this way, instead of leaving img.Set to drop the rest.
+func clipLineSteps(length, delta, a1, aDir, aMin, aMax, b1, bDir, bMin, bMax int) (lo, hi int) {
+	lo, hi = 0, length
+	// a1 + aDir*s in [aMin, aMax)
+	if aDir > 0 {
+		lo, hi = max(lo, aMin-a1), min(hi, aMax-1-a1)
+	} else {
+		lo, hi = max(lo, a1-aMax+1), min(hi, a1-aMin)
+	}
+	// b1 + bDir*lineMinor(s) in [bMin, bMax), where lineMinor never decreases with s
+	from, to := bMin-b1, bMax-1-b1
+	if bDir < 0 {
+		from, to = b1-bMax+1, b1-bMin
+	}
+	if to < 0 || (delta == 0 && from > 0) {
+		return 1, 0
+	}
+	if delta > 0 {
+		if from > 0 {
+			lo = max(lo, (2*length*from-length+2*delta)/(2*delta))
+		}
+		hi = min(hi, (2*length*(to+1)-length)/(2*delta))
+	}
+	return lo, hi
+}
+
+// How far a Bresenham line has moved along its minor axis after s steps along its major axis
+func lineMinor(s, length, delta int) int {
+	if length == 0 {
+		return 0
+	}
+	return (2*delta*s + length - 1) / (2 * length)
+}
+
 func lineOctant0or3(img *image.RGBA, x1, y1, absdx, dy, xDirection int, color color.RGBA) {
+	b := img.Bounds()
+	lo, hi := clipLineSteps(absdx, dy, x1, xDirection, b.Min.X, b.Max.X, y1, 1, b.Min.Y, b.Max.Y)
+	if lo > hi {
+		return
+	}
 	deltaYx2 := 2 * dy
 	DeltaYx2MinusDeltaXx2 := deltaYx2 - 2*absdx
 	// Error actually represents how far off we are from the top of the current pixel, where -2*absdx is the bottom,
 	// and 0 is the top. So its misnamed, but the point is that algorithm only needs integer math.
-	Error := -absdx + deltaYx2
+	// Starting from step lo, it's what the walk from step 0 would have left it at.
+	minor := lineMinor(lo, absdx, dy)
+	Error := -absdx + deltaYx2*(lo+1) - 2*absdx*minor
+	x1 += xDirection * lo
+	y1 += minor
 	img.Set(x1, y1, color)
-	x2 := x1 + xDirection*absdx
-	for x1 != x2 {
+	for s := lo; s < hi; s++ {
 		if Error > 0 {
 			y1 += 1
 			Error += DeltaYx2MinusDeltaXx2
@@ -63,13 +290,20 @@ func lineOctant0or3(img *image.RGBA, x1, y1, absdx, dy, xDirection int, color co
 }
 
 func lineOctant1or2(img *image.RGBA, x1, y1, absdx, dy, xDirection int, color color.RGBA) {
+	b := img.Bounds()
+	lo, hi := clipLineSteps(dy, absdx, y1, 1, b.Min.Y, b.Max.Y, x1, xDirection, b.Min.X, b.Max.X)
+	if lo > hi {
+		return
+	}
 	DeltaXx2 := 2 * absdx
 	DeltaXx2MinusDeltaYx2 := DeltaXx2 - 2*dy
 	// Here -2*dy is the left, and 0 is the right(if we're moving to the right).
-	Error := DeltaXx2 - dy
+	minor := lineMinor(lo, dy, absdx)
+	Error := -dy + DeltaXx2*(lo+1) - 2*dy*minor
+	x1 += xDirection * minor
+	y1 += lo
 	img.Set(x1, y1, color)
-	y2 := y1 + dy
-	for y1 != y2 {
+	for s := lo; s < hi; s++ {
 		if Error > 0 {
 			x1 += xDirection
 			Error += DeltaXx2MinusDeltaYx2
@@ -123,17 +357,21 @@ type Mat2d = struct{ xx, xy, yx, yy float64 }
 var arrowLeftRotMatrix = Mat2d{xx: -.866, xy: -.5, yx: .5, yy: -.866}
 var arrowRightRotMatrix = Mat2d{xx: -.866, xy: .5, yx: -.5, yy: -.866}
 
-func drawDirectedLine(img *image.RGBA, x1, y1, x2, y2 int, color color.RGBA, arrowcolor color.RGBA) {
-	imgW, imgH := img.Bounds().Max.X, img.Bounds().Max.Y
+// The arrowhead's tip touches a node of radius nodeR, and its sides are R long. Arrowheads are
+// clamped to an imgW x imgH picture, which img may be a tile of.
+func drawDirectedLine(img *image.RGBA, x1, y1, x2, y2 int, color color.RGBA, arrowcolor color.RGBA, nodeR int, R float64, imgW, imgH int) {
 	drawLine(img, x1, y1, x2, y2, color)
 	dx := float64(x2 - x1)
 	dy := float64(y2 - y1)
 	r := math.Sqrt(dx*dx + dy*dy)
-	R := float64(20.0)
+	if r == 0 {
+		// self-loop or overlapping nodes, no direction to point in
+		return
+	}
 	rx := dx / r
 	ry := dy / r
-	tipX := float64(x2) - float64(nodeRadius)*rx
-	tipY := float64(y2) - float64(nodeRadius)*ry
+	tipX := float64(x2) - float64(nodeR)*rx
+	tipY := float64(y2) - float64(nodeR)*ry
 	// Apply a rotation matrix to find the location of the "arrowhead" points...
 	// This won't draw the arrows quite right if they go off the screen: the proper thing
 	// todo would be to compute the intersection with the boundary lines.
@@ -148,7 +386,7 @@ func drawDirectedLine(img *image.RGBA, x1, y1, x2, y2 int, color color.RGBA, arr
 
 // filled in circle at (x, y) with radius r
 func drawCircle(img *image.RGBA, x, y, r int, color color.RGBA) {
-	// not quite Bresenham quality, Todo: improve or antialias
+	// not quite Bresenham quality; drawCircleAA is the smooth version
 	for i := 0; i <= r; i++ {
 		for j := 0; j <= r; j++ {
 			if i*i+j*j <= r*r+1 {
@@ -193,53 +431,213 @@ func getBoundary(graph []PosNode) Boundary {
 }
 
 func translateCoords(x, y float32, boundary Boundary, imgW, imgH int) (int, int) {
-	xScale := float32(imgW-2*nodeRadius) / (boundary.Right - boundary.Left)
-	yScale := float32(imgH-2*nodeRadius) / (boundary.Top - boundary.Bottom)
+	return translateCoordsInset(x, y, boundary, imgW, imgH, nodeRadius)
+}
+
+// Like translateCoords, but keeps node centers inset pixels away from the image edges
+func translateCoordsInset(x, y float32, boundary Boundary, imgW, imgH, inset int) (int, int) {
+	xScale := float32(imgW-2*inset) / (boundary.Right - boundary.Left)
+	yScale := float32(imgH-2*inset) / (boundary.Top - boundary.Bottom)
 
-	xOffset := nodeRadius + int(xScale*(x-boundary.Left))
-	yOffset := nodeRadius + int(yScale*(boundary.Top-y))
+	xOffset := inset + int(xScale*(x-boundary.Left))
+	yOffset := inset + int(yScale*(boundary.Top-y))
 
 	// xx remove
 	// assert(xOffset >= 0 && xOffset < imgW && yOffset >= 0 && yOffset < imgH, "out of bounds")
 	return xOffset, yOffset
 }
 
-func drawEdges(img *image.RGBA, graph []PosNode, boundary Boundary, directed bool) {
-	imgW, imgH := img.Bounds().Max.X, img.Bounds().Max.Y
-	for _, node := range graph {
-		for _, edge := range node.Edges {
-			x1p, y1p := translateCoords(node.X, node.Y, boundary, imgW, imgH)
-			x2p, y2p := translateCoords(graph[edge].X, graph[edge].Y, boundary, imgW, imgH)
+// An edge as it is drawn: the pixels it runs through, bends included, and the node it ends at
+type pixelEdge struct {
+	path   []image.Point
+	target int
+}
+
+// Draws edges, ending at nodes radii[target] pixels across. img may be a tile of the imgW x
+// imgH picture.
+func drawEdges(img *image.RGBA, radii []int, edges []pixelEdge, directed bool, opts RenderOptions, imgW, imgH int) {
+	width := opts.EdgeWidth * opts.Scale
+	ec, ac := fade(edgeColor, opts.EdgeOpacity), fade(arrowColor, opts.EdgeOpacity)
+	for _, e := range edges {
+		last := len(e.path) - 1
+		if opts.Antialias && last > 1 {
+			// A bundled edge, drawn in one go so it's evenly translucent
+			points := make([][2]float64, len(e.path))
+			for k, p := range e.path {
+				points[k] = [2]float64{float64(p.X), float64(p.Y)}
+			}
+			drawPolylineAA(img, points, width, ec)
 			if directed {
-				drawDirectedLine(img, x1p, y1p, x2p, y2p, edgeColor, arrowColor)
+				p1, p2 := points[last-1], points[last]
+				drawArrowheadAA(img, p1[0], p1[1], p2[0], p2[1], width, ac, float64(radii[e.target]), 20*opts.Scale)
+			}
+			continue
+		}
+		// Bends are plain lines; only the last stretch gets the arrowhead
+		for k := 0; k < last-1; k++ {
+			p1, p2 := e.path[k], e.path[k+1]
+			if opts.Antialias {
+				drawLineAA(img, float64(p1.X), float64(p1.Y), float64(p2.X), float64(p2.Y), width, ec)
 			} else {
-				drawLine(img, x1p, y1p, x2p, y2p, edgeColor)
+				drawLine(img, p1.X, p1.Y, p2.X, p2.Y, edgeColor)
 			}
 		}
+		p1, p2 := e.path[last-1], e.path[last]
+		radius := radii[e.target]
+		switch {
+		case opts.Antialias && directed:
+			drawDirectedLineAA(img, float64(p1.X), float64(p1.Y), float64(p2.X), float64(p2.Y), width, ec, ac, float64(radius), 20*opts.Scale)
+		case opts.Antialias:
+			drawLineAA(img, float64(p1.X), float64(p1.Y), float64(p2.X), float64(p2.Y), width, ec)
+		case directed:
+			drawDirectedLine(img, p1.X, p1.Y, p2.X, p2.Y, edgeColor, arrowColor, radius, 20*opts.Scale, imgW, imgH)
+		default:
+			drawLine(img, p1.X, p1.Y, p2.X, p2.Y, edgeColor)
+		}
 	}
 }
 
-func drawNodes(img *image.RGBA, graph PosGraph, boundary Boundary) {
-	imgW, imgH := img.Bounds().Max.X, img.Bounds().Max.Y
-	for _, node := range graph {
-		xp, yp := translateCoords(node.X, node.Y, boundary, imgW, imgH)
-		drawCircle(img, xp, yp, nodeRadius, nodeColor)
+// Draws the nodes of graph listed in nodes, with node i at pixel pos[i] and radii[i] pixels
+// across
+func drawNodes(img *image.RGBA, graph PosGraph, pos []image.Point, radii []int, nodes []int, opts RenderOptions) {
+	for _, i := range nodes {
+		if opts.Antialias {
+			drawCircleAA(img, float64(pos[i].X), float64(pos[i].Y), float64(radii[i]), graph[i].fill())
+		} else {
+			drawCircle(img, pos[i].X, pos[i].Y, radii[i], graph[i].fill())
+		}
 	}
 }
 
-/*
-Todo: Rendering the graph can actually be done in parallel because we don't mind
+/***** Parallel tiled rendering *****/
 
-	drawing things on top of each other as long as the edge phase and the node phase are
-	separate.
-*/
-func drawGraph(img *image.RGBA, graph PosGraph, directed bool) {
+// Side of the square tiles drawGraph splits the image into
+const renderTileSize = 256
+
+// Adds item to the bin of every tile that box overlaps. Tiles are numbered row by row,
+// tilesX to a row.
+func binByBox(bins [][]int, box image.Rectangle, tileSize, tilesX, tilesY int, item int) {
+	x0, y0 := max(box.Min.X/tileSize, 0), max(box.Min.Y/tileSize, 0)
+	x1, y1 := min(box.Max.X/tileSize, tilesX-1), min(box.Max.Y/tileSize, tilesY-1)
+	for ty := y0; ty <= y1; ty++ {
+		for tx := x0; tx <= x1; tx++ {
+			bins[ty*tilesX+tx] = append(bins[ty*tilesX+tx], item)
+		}
+	}
+}
+
+// Runs draw on every tile of img at once, each with its own goroutine, and waits for them
+func forEachTile(img *image.RGBA, tileSize, tilesX, tilesY int, draw func(tile *image.RGBA, t int)) {
+	var wg sync.WaitGroup
+	for t := 0; t < tilesX*tilesY; t++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tx, ty := t%tilesX, t/tilesX
+			rect := image.Rect(tx*tileSize, ty*tileSize, (tx+1)*tileSize, (ty+1)*tileSize).Intersect(img.Rect)
+			// Shares img's pixels, and clips everything drawn to the tile
+			draw(img.SubImage(rect).(*image.RGBA), t)
+		}()
+	}
+	wg.Wait()
+}
+
+// Draws the graph with the image split into tiles. Each edge and node is binned into every
+// tile its bounding box touches, and the tiles are drawn in parallel, each clipped to its own
+// pixels. Within a tile, edges and nodes are drawn in the same order as a serial pass, and all
+// edges finish before any node is drawn, so the picture is the same whatever the tile size.
+func drawGraphTiled(img *image.RGBA, graph PosGraph, directed bool, opts RenderOptions, tileSize int) {
+	imgW, imgH := img.Bounds().Max.X, img.Bounds().Max.Y
 	boundary := getBoundary(graph)
-	drawEdges(img, graph, boundary, directed)
-	drawNodes(img, graph, boundary)
+	inset := opts.inset(graph)
+	pos := make([]image.Point, len(graph))
+	radii := make([]int, len(graph))
+	for i, node := range graph {
+		pos[i].X, pos[i].Y = translateCoordsInset(node.X, node.Y, boundary, imgW, imgH, inset)
+		radii[i] = opts.radiusOf(node)
+	}
+
+	// Anti-aliased edges blend, so each undirected edge is drawn once; the fast path draws
+	// both copies as it always has
+	var edges []pixelEdge
+	addEdge := func(u, v, j int) {
+		e := pixelEdge{path: []image.Point{pos[u]}, target: v}
+		if j < len(graph[u].Bends) {
+			for _, b := range graph[u].Bends[j] {
+				x, y := translateCoordsInset(float32(b.X), float32(b.Y), boundary, imgW, imgH, inset)
+				e.path = append(e.path, image.Pt(x, y))
+			}
+		}
+		e.path = append(e.path, pos[v])
+		edges = append(edges, e)
+	}
+	if opts.Antialias {
+		forEachEdge(graph, directed, addEdge)
+	} else {
+		for u := range graph {
+			for j := 0; j < graph[u].degree(); j++ {
+				addEdge(u, graph[u].neighbor(j), j)
+			}
+		}
+	}
+
+	tilesX, tilesY := (imgW+tileSize-1)/tileSize, (imgH+tileSize-1)/tileSize
+	// Room for the stroke, and for arrowheads, which stick out sideways from the edge
+	pad := int(math.Ceil(opts.EdgeWidth*opts.Scale/2)) + 2
+	if directed {
+		pad += int(math.Ceil(20 * opts.Scale))
+	}
+	edgeBins := make([][]int, tilesX*tilesY)
+	for i, e := range edges {
+		box := image.Rectangle{e.path[0], e.path[0]}
+		for _, p := range e.path[1:] {
+			box.Min.X, box.Min.Y = min(box.Min.X, p.X), min(box.Min.Y, p.Y)
+			box.Max.X, box.Max.Y = max(box.Max.X, p.X), max(box.Max.Y, p.Y)
+		}
+		binByBox(edgeBins, box.Inset(-pad), tileSize, tilesX, tilesY, i)
+	}
+	nodeBins := make([][]int, tilesX*tilesY)
+	for i, p := range pos {
+		box := image.Rectangle{p, p}
+		binByBox(nodeBins, box.Inset(-(radii[i] + 2)), tileSize, tilesX, tilesY, i)
+	}
+	var labelMasks []*image.Alpha
+	var labelBoxes []image.Rectangle
+	var labelBins [][]int
+	if opts.showLabels(graph, imgW, imgH) {
+		labelMasks, labelBoxes = rasterizeLabels(graph, pos, radii, image.Rect(0, 0, imgW, imgH), opts)
+		labelBins = make([][]int, tilesX*tilesY)
+		for i, box := range labelBoxes {
+			if labelMasks[i] != nil {
+				binByBox(labelBins, box, tileSize, tilesX, tilesY, i)
+			}
+		}
+	}
+
+	forEachTile(img, tileSize, tilesX, tilesY, func(tile *image.RGBA, t int) {
+		draw.Draw(tile, tile.Rect, &image.Uniform{opts.Background}, image.Point{}, draw.Src)
+		binned := make([]pixelEdge, len(edgeBins[t]))
+		for j, i := range edgeBins[t] {
+			binned[j] = edges[i]
+		}
+		drawEdges(tile, radii, binned, directed, opts, imgW, imgH)
+	})
+	forEachTile(img, tileSize, tilesX, tilesY, func(tile *image.RGBA, t int) {
+		drawNodes(tile, graph, pos, radii, nodeBins[t], opts)
+		if labelBins != nil {
+			drawLabels(tile, labelMasks, labelBoxes, labelBins[t], opts)
+		}
+	})
+	if len(opts.Legend) > 0 {
+		drawLegend(img, opts)
+	}
+}
+
+func drawGraph(img *image.RGBA, graph PosGraph, directed bool, opts RenderOptions) {
+	drawGraphTiled(img, graph, directed, opts, renderTileSize)
 }
 
-func run(window *app.Window, graph PosGraph, directed bool) error {
+func run(window *app.Window, graph PosGraph, directed bool, opts RenderOptions) error {
 	var ops op.Ops
 	for {
 		switch e := window.Event().(type) {
@@ -247,8 +645,7 @@ func run(window *app.Window, graph PosGraph, directed bool) error {
 			return e.Err
 		case app.FrameEvent:
 			img := image.NewRGBA(image.Rect(0, 0, e.Size.X, e.Size.Y))
-			draw.Draw(img, img.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
-			drawGraph(img, graph, directed)
+			drawGraph(img, graph, directed, opts)
 
 			// see https://gioui.org/doc/architecture/drawing
 			paint.NewImageOp(img).Add(&ops)
@@ -258,12 +655,12 @@ func run(window *app.Window, graph PosGraph, directed bool) error {
 	}
 }
 
-func RenderGUI(graph PosGraph, directed bool) {
+func RenderGUI(graph PosGraph, directed bool, opts RenderOptions) {
 	fmt.Println("Starting ui...")
 	go func() {
 		window := new(app.Window)
 		window.Option(app.Title("Graphs"))
-		err := run(window, graph, directed)
+		err := run(window, graph, directed, opts)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -272,13 +669,16 @@ func RenderGUI(graph PosGraph, directed bool) {
 	app.Main()
 }
 
-func RenderPNG(graph PosGraph, directed bool) {
-	img := image.NewRGBA(image.Rect(0, 0, 2000, 2000))
-	draw.Draw(img, img.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
-	drawGraph(img, graph, directed)
-	out, _ := os.Create("output.png")
-	png.Encode(out, img)
-	out.Close()
+// Draws the graph into a new image sized by opts
+func renderImage(graph PosGraph, directed bool, opts RenderOptions) *image.RGBA {
+	w, h := opts.imageSize(graph)
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	drawGraph(img, graph, directed, opts)
+	return img
+}
+
+func writePNG(w io.Writer, graph PosGraph, directed bool, opts RenderOptions) error {
+	return png.Encode(w, renderImage(graph, directed, opts))
 }
 
 /* Refs:
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"testing"
+)
+
+func TestParseColor(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		want color.RGBA
+	}{
+		{"white", color.RGBA{255, 255, 255, 255}},
+		{"transparent", color.RGBA{}},
+		{"#f80", color.RGBA{255, 136, 0, 255}},
+		{"#102030", color.RGBA{16, 32, 48, 255}},
+		// premultiplied
+		{"#ff000080", color.RGBA{128, 0, 0, 128}},
+	} {
+		got, err := parseColor(c.in)
+		if err != nil || got != c.want {
+			t.Errorf("parseColor(%q) = %v, %v, want %v", c.in, got, err, c.want)
+		}
+	}
+	for _, bad := range []string{"", "red", "102030", "#12", "#gggggg"} {
+		if _, err := parseColor(bad); err == nil {
+			t.Errorf("parseColor(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestImageSize(t *testing.T) {
+	// twice as wide as it is tall
+	graph := PosGraph{{X: 0, Y: 0}, {X: 200, Y: 100}}
+	for _, c := range []struct {
+		opts RenderOptions
+		w, h int
+	}{
+		{RenderOptions{Width: 300, Height: 200, Scale: 1}, 300, 200},
+		{RenderOptions{Width: 224, Scale: 1}, 224, 124},
+		{RenderOptions{Height: 124, Scale: 1}, 224, 124},
+		{RenderOptions{Width: 224, Margin: 10, Scale: 1}, 224, 134},
+		{RenderOptions{Width: 300, Height: 200, Scale: 2}, 600, 400},
+	} {
+		w, h := c.opts.imageSize(graph)
+		if w != c.w || h != c.h {
+			t.Errorf("imageSize(%+v) = %d x %d, want %d x %d", c.opts, w, h, c.w, c.h)
+		}
+	}
+}
+
+func TestWritePNG(t *testing.T) {
+	var buf bytes.Buffer
+	opts := RenderOptions{Width: 100, Height: 50, Margin: 5, Scale: 1}
+	if err := writePNG(&buf, testgraph, true, opts); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 100, 50) {
+		t.Errorf("image bounds = %v, want 100 x 50", img.Bounds())
+	}
+	if _, _, _, a := img.At(1, 1).RGBA(); a != 0 {
+		t.Errorf("corner of a transparent image has alpha %d", a)
+	}
+	// node 0 is at the bottom left, inset by the margin and the node radius
+	if got := color.RGBAModel.Convert(img.At(17, 50-17-1)); got != nodeColor {
+		t.Errorf("expected a node at (17, 32), got %v", got)
+	}
+}
+
+func TestDrawGraphTiled(t *testing.T) {
+	// enough crossing edges and overlapping nodes that drawing order shows
+	graph := make(PosGraph, 40)
+	for i := range graph {
+		graph[i] = PosNode{X: float32(i * 37 % 23), Y: float32(i * 11 % 17)}
+		graph[i].Edges = []int{(i + 1) % 40, (i * 7) % 40, (i + 20) % 40}
+	}
+	for _, directed := range []bool{false, true} {
+		for _, antialias := range []bool{false, true} {
+			opts := RenderOptions{Width: 301, Height: 203, Scale: 1, Antialias: antialias, EdgeWidth: 2.5, EdgeOpacity: 0.4,
+				Background: color.RGBA{255, 255, 255, 255}}
+			serial := image.NewRGBA(image.Rect(0, 0, 301, 203))
+			drawGraphTiled(serial, graph, directed, opts, 1000)
+			for _, tileSize := range []int{7, 64} {
+				tiled := image.NewRGBA(image.Rect(0, 0, 301, 203))
+				drawGraphTiled(tiled, graph, directed, opts, tileSize)
+				if !bytes.Equal(serial.Pix, tiled.Pix) {
+					t.Errorf("directed=%v antialias=%v: %d pixel tiles differ from one tile", directed, antialias, tileSize)
+				}
+			}
+		}
+	}
+}
+
+// A line drawn into a tile, which clips it before walking it, sets the same pixels as the
+// same line drawn whole onto a canvas it fits on
+func TestDrawLineClipped(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	tile := image.Rect(20, 30, 45, 50)
+	black := color.RGBA{0, 0, 0, 255}
+	for i := 0; i < 2000; i++ {
+		x1, y1, x2, y2 := rng.Intn(80)-5, rng.Intn(80)-5, rng.Intn(80)-5, rng.Intn(80)-5
+		whole := image.NewRGBA(image.Rect(-5, -5, 75, 75))
+		drawLine(whole, x1, y1, x2, y2, black)
+		clipped := image.NewRGBA(tile)
+		drawLine(clipped, x1, y1, x2, y2, black)
+		for y := tile.Min.Y; y < tile.Max.Y; y++ {
+			for x := tile.Min.X; x < tile.Max.X; x++ {
+				if whole.RGBAAt(x, y) != clipped.RGBAAt(x, y) {
+					t.Fatalf("line (%d,%d)-(%d,%d) differs at (%d,%d)", x1, y1, x2, y2, x, y)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,455 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/clarkep/ppa-final/layout/sugiyama"
+	"github.com/go-text/typesetting/font"
+	"github.com/go-text/typesetting/shaping"
+)
+
+/***** Node style mapping *****/
+
+// How node colors and sizes are picked from the graph
+type StyleOptions struct {
+	// What colors the nodes: "degree", "level", "component", or the name of a node attribute.
+	// Empty leaves every node nodeColor.
+	ColorBy string
+	// What sizes the nodes: "degree" or a numeric node attribute. Empty keeps every node the
+	// same size.
+	SizeBy string
+	// Name of the palette in palettes; empty picks tab10 for categories and viridis for
+	// numbers
+	Palette string
+}
+
+// Nodes with no value for the ColorBy attribute
+var missingColor = color.RGBA{170, 170, 170, 255}
+
+// Range of node radii, as multiples of the usual one, that SizeBy values are mapped onto
+const minNodeScale, maxNodeScale = 0.5, 2.0
+
+// At most this many categories are listed in a legend
+const legendMaxEntries = 12
+
+// A color in a legend and what it stands for. Entries without a color (A == 0) are only text.
+type LegendEntry struct {
+	Label string
+	Color color.RGBA
+}
+
+type palette struct {
+	Colors []color.RGBA
+	// Sequential palettes run from low to high and are sampled evenly for categories;
+	// qualitative ones are used in order and interpolated for numbers
+	Sequential bool
+}
+
+func hexPalette(sequential bool, hex ...string) palette {
+	p := palette{Sequential: sequential}
+	for _, h := range hex {
+		c, err := parseColor(h)
+		if err != nil {
+			panic(err)
+		}
+		p.Colors = append(p.Colors, c)
+	}
+	return p
+}
+
+// Built-in palettes: matplotlib's tab10, ColorBrewer's Set2, Dark2 and Paired, and the
+// viridis, plasma and cividis color maps sampled at 9 points
+var palettes = map[string]palette{
+	"tab10": hexPalette(false, "#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd",
+		"#8c564b", "#e377c2", "#7f7f7f", "#bcbd22", "#17becf"),
+	"set2": hexPalette(false, "#66c2a5", "#fc8d62", "#8da0cb", "#e78ac3", "#a6d854",
+		"#ffd92f", "#e5c494", "#b3b3b3"),
+	"dark2": hexPalette(false, "#1b9e77", "#d95f02", "#7570b3", "#e7298a", "#66a61e",
+		"#e6ab02", "#a6761d", "#666666"),
+	"paired": hexPalette(false, "#a6cee3", "#1f78b4", "#b2df8a", "#33a02c", "#fb9a99",
+		"#e31a1c", "#fdbf6f", "#ff7f00", "#cab2d6", "#6a3d9a", "#ffff99", "#b15928"),
+	"viridis": hexPalette(true, "#440154", "#472d7b", "#3b528b", "#2c728e", "#21918c",
+		"#28ae80", "#5ec962", "#addc30", "#fde725"),
+	"plasma": hexPalette(true, "#0d0887", "#4c02a1", "#7e03a8", "#a92395", "#cc4778",
+		"#e56b5d", "#f89441", "#fdc328", "#f0f921"),
+	"cividis": hexPalette(true, "#00224e", "#123570", "#3b496c", "#575d6d", "#707173",
+		"#8a8779", "#a69d75", "#c4b56c", "#fee838"),
+}
+
+// Color at t in [0, 1] along the palette, interpolating between its colors
+func (p palette) at(t float64) color.RGBA {
+	t = min(max(t, 0), 1) * float64(len(p.Colors)-1)
+	i := min(int(t), len(p.Colors)-2)
+	if i < 0 {
+		return p.Colors[0]
+	}
+	f := t - float64(i)
+	a, b := p.Colors[i], p.Colors[i+1]
+	mix := func(x, y uint8) uint8 { return uint8(float64(x)*(1-f) + float64(y)*f + 0.5) }
+	return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), mix(a.A, b.A)}
+}
+
+// Color of category i out of n
+func (p palette) category(i, n int) color.RGBA {
+	if p.Sequential {
+		return p.at(float64(i) / float64(max(n-1, 1)))
+	}
+	return p.Colors[i%len(p.Colors)]
+}
+
+// Total degree of every node: out plus in for directed graphs
+func nodeDegrees(graph Adjacency, directed bool) []float64 {
+	n := graph.NumNodes()
+	deg := make([]float64, n)
+	for u := 0; u < n; u++ {
+		deg[u] += float64(graph.Degree(u))
+		if directed {
+			for j := 0; j < graph.Degree(u); j++ {
+				deg[graph.Neighbor(u, j)]++
+			}
+		}
+	}
+	return deg
+}
+
+// Level of every node. For directed graphs this is the layer the Sugiyama layout puts it in,
+// with cycles broken the same way; for undirected ones it's the BFS depth from the first node
+// of its component.
+func nodeLevels(graph Adjacency, directed bool) []float64 {
+	n := graph.NumNodes()
+	levels := make([]float64, n)
+	if directed {
+		for i, level := range sugiyama.Levels(graph) {
+			levels[i] = float64(level)
+		}
+		return levels
+	}
+	seen := make([]bool, n)
+	for root := 0; root < n; root++ {
+		if seen[root] {
+			continue
+		}
+		seen[root] = true
+		queue := []int{root}
+		for len(queue) > 0 {
+			u := queue[0]
+			queue = queue[1:]
+			for j := 0; j < graph.Degree(u); j++ {
+				if v := graph.Neighbor(u, j); !seen[v] {
+					seen[v] = true
+					levels[v] = levels[u] + 1
+					queue = append(queue, v)
+				}
+			}
+		}
+	}
+	return levels
+}
+
+// Weakly connected component of every node, numbered from the largest component down
+func nodeComponents(graph Adjacency) []int {
+	n := graph.NumNodes()
+	parent := make([]int, n)
+	for i := range parent {
+		parent[i] = i
+	}
+	find := func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+	for u := 0; u < n; u++ {
+		for j := 0; j < graph.Degree(u); j++ {
+			if a, b := find(u), find(graph.Neighbor(u, j)); a != b {
+				parent[max(a, b)] = min(a, b)
+			}
+		}
+	}
+
+	size := make([]int, n)
+	for i := range parent {
+		size[find(i)]++
+	}
+	var roots []int
+	for i := range parent {
+		if parent[i] == i {
+			roots = append(roots, i)
+		}
+	}
+	sort.SliceStable(roots, func(a, b int) bool { return size[roots[a]] > size[roots[b]] })
+	number := make([]int, n)
+	for k, r := range roots {
+		number[r] = k
+	}
+	comp := make([]int, n)
+	for i := range comp {
+		comp[i] = number[find(i)]
+	}
+	return comp
+}
+
+// Values of a node attribute, as strings with "" where it's missing. The error says if no node
+// has it.
+func nodeAttrValues(data *GraphData, n int, name string) ([]string, error) {
+	values := make([]string, n)
+	found := false
+	for i := range values {
+		if i < len(data.Attrs.Nodes) {
+			values[i] = data.Attrs.Nodes[i][name]
+			found = found || values[i] != ""
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("no node has a %q attribute", name)
+	}
+	return values, nil
+}
+
+// Parses values as numbers; ok is false if any non-empty value isn't one
+func numericValues(values []string) (nums []float64, ok bool) {
+	nums = make([]float64, len(values))
+	for i, v := range values {
+		if v == "" {
+			nums[i] = math.NaN()
+			continue
+		}
+		x, err := strconv.ParseFloat(v, 64)
+		if err != nil || math.IsNaN(x) || math.IsInf(x, 0) {
+			return nil, false
+		}
+		nums[i] = x
+	}
+	return nums, true
+}
+
+// Smallest and largest of the non-NaN values
+func valueRange(nums []float64) (lo, hi float64) {
+	lo, hi = math.Inf(1), math.Inf(-1)
+	for _, x := range nums {
+		if !math.IsNaN(x) {
+			lo, hi = min(lo, x), max(hi, x)
+		}
+	}
+	return lo, hi
+}
+
+// Colors nodes by number along a palette. The legend shows the ends and three points between,
+// or every value if they're a few whole numbers.
+func colorByNumber(graph PosGraph, nums []float64, p palette) []LegendEntry {
+	lo, hi := valueRange(nums)
+	t := func(x float64) float64 {
+		if hi == lo {
+			return 0
+		}
+		return (x - lo) / (hi - lo)
+	}
+	missing := false
+	for i, x := range nums {
+		if math.IsNaN(x) {
+			graph[i].Color = missingColor
+			missing = true
+		} else {
+			graph[i].Color = p.at(t(x))
+		}
+	}
+
+	var legend []LegendEntry
+	if lo == math.Trunc(lo) && hi == math.Trunc(hi) && hi-lo < legendMaxEntries {
+		// Counting steps rather than x itself, which x++ can't move past 2^53
+		for k := 0; k <= int(hi-lo); k++ {
+			x := lo + float64(k)
+			legend = append(legend, LegendEntry{strconv.FormatFloat(x, 'g', -1, 64), p.at(t(x))})
+		}
+	} else {
+		for k := 0; k <= 4; k++ {
+			x := lo + (hi-lo)*float64(k)/4
+			legend = append(legend, LegendEntry{strconv.FormatFloat(x, 'g', 3, 64), p.at(t(x))})
+		}
+	}
+	if missing {
+		legend = append(legend, LegendEntry{"(none)", missingColor})
+	}
+	return legend
+}
+
+// Colors nodes by category, given as category numbers with -1 for none, and names the
+// categories in the legend
+func colorByCategory(graph PosGraph, cats []int, names []string, p palette) []LegendEntry {
+	missing := false
+	for i, c := range cats {
+		if c < 0 {
+			graph[i].Color = missingColor
+			missing = true
+		} else {
+			graph[i].Color = p.category(c, len(names))
+		}
+	}
+	var legend []LegendEntry
+	for c, name := range names {
+		if c == legendMaxEntries {
+			legend = append(legend, LegendEntry{Label: fmt.Sprintf("%d more", len(names)-c)})
+			break
+		}
+		legend = append(legend, LegendEntry{name, p.category(c, len(names))})
+	}
+	if missing {
+		legend = append(legend, LegendEntry{"(none)", missingColor})
+	}
+	return legend
+}
+
+// Sorts attribute values into categories, in numeric order if they're all numbers
+func categorize(values []string) ([]int, []string) {
+	var names []string
+	seen := make(map[string]bool)
+	for _, v := range values {
+		if v != "" && !seen[v] {
+			seen[v] = true
+			names = append(names, v)
+		}
+	}
+	if nums, ok := numericValues(names); ok {
+		sort.Sort(byNumber{names, nums})
+	} else {
+		sort.Strings(names)
+	}
+	index := make(map[string]int, len(names))
+	for c, name := range names {
+		index[name] = c
+	}
+	cats := make([]int, len(values))
+	for i, v := range values {
+		cats[i] = -1
+		if v != "" {
+			cats[i] = index[v]
+		}
+	}
+	return cats, names
+}
+
+type byNumber struct {
+	names []string
+	nums  []float64
+}
+
+func (s byNumber) Len() int           { return len(s.names) }
+func (s byNumber) Less(i, j int) bool { return s.nums[i] < s.nums[j] }
+func (s byNumber) Swap(i, j int) {
+	s.names[i], s.names[j] = s.names[j], s.names[i]
+	s.nums[i], s.nums[j] = s.nums[j], s.nums[i]
+}
+
+// Sets the nodes' Color and Size as opts asks, and returns the legend for the colors.
+// Attributes whose values are all numbers are colored along the palette, and anything else
+// by category; components are always categories.
+func applyStyle(graph PosGraph, data *GraphData, opts StyleOptions) ([]LegendEntry, error) {
+	adj := data.adjacency()
+	var legend []LegendEntry
+	if opts.ColorBy != "" {
+		// main checks that the palette is one of palettes
+		p := palettes[opts.Palette]
+		pick := func(def string) palette {
+			if opts.Palette == "" {
+				return palettes[def]
+			}
+			return p
+		}
+		switch opts.ColorBy {
+		case "degree":
+			legend = colorByNumber(graph, nodeDegrees(adj, data.Directed), pick("viridis"))
+		case "level":
+			legend = colorByNumber(graph, nodeLevels(adj, data.Directed), pick("viridis"))
+		case "component":
+			comp := nodeComponents(adj)
+			n := 0
+			for _, c := range comp {
+				n = max(n, c+1)
+			}
+			names := make([]string, n)
+			for c := range names {
+				names[c] = fmt.Sprintf("component %d", c+1)
+			}
+			legend = colorByCategory(graph, comp, names, pick("tab10"))
+		default:
+			values, err := nodeAttrValues(data, len(graph), opts.ColorBy)
+			if err != nil {
+				return nil, err
+			}
+			cats, names := categorize(values)
+			// A handful of distinct numbers, like a class id, still reads best as categories
+			if nums, ok := numericValues(values); ok && len(names) > len(palettes["tab10"].Colors) {
+				legend = colorByNumber(graph, nums, pick("viridis"))
+			} else {
+				legend = colorByCategory(graph, cats, names, pick("tab10"))
+			}
+		}
+	}
+
+	if opts.SizeBy != "" {
+		var nums []float64
+		if opts.SizeBy == "degree" {
+			nums = nodeDegrees(adj, data.Directed)
+		} else {
+			values, err := nodeAttrValues(data, len(graph), opts.SizeBy)
+			if err != nil {
+				return nil, err
+			}
+			var ok bool
+			if nums, ok = numericValues(values); !ok {
+				return nil, fmt.Errorf("can't size nodes by %q: its values aren't all numbers", opts.SizeBy)
+			}
+		}
+		lo, hi := valueRange(nums)
+		for i, x := range nums {
+			switch {
+			case math.IsNaN(x):
+				graph[i].Size = 1
+			case hi == lo:
+				graph[i].Size = 1
+			default:
+				graph[i].Size = float32(minNodeScale + (maxNodeScale-minNodeScale)*(x-lo)/(hi-lo))
+			}
+		}
+	}
+	return legend, nil
+}
+
+// Draws opts.Legend in the top right corner of img: a swatch and a line of text per entry, on
+// a translucent box
+func drawLegend(img *image.RGBA, opts RenderOptions) {
+	size := opts.FontSize * opts.Scale
+	face := font.NewFace(labelFont())
+	var shaper shaping.HarfbuzzShaper
+	masks := make([]*image.Alpha, len(opts.Legend))
+	textW, lineH := 0, 0
+	for i, entry := range opts.Legend {
+		masks[i] = rasterizeLabel(&shaper, face, entry.Label, size)
+		textW, lineH = max(textW, masks[i].Rect.Dx()), max(lineH, masks[i].Rect.Dy())
+	}
+	pad := round64(size / 2)
+	swatch := lineH * 2 / 3
+	boxW := pad + swatch + pad + textW + pad
+	boxH := pad + len(masks)*lineH + pad
+	inset := round64(float64(opts.Margin)*opts.Scale) + pad
+	box := image.Rect(img.Rect.Max.X-inset-boxW, img.Rect.Min.Y+inset, img.Rect.Max.X-inset, img.Rect.Min.Y+inset+boxH)
+
+	draw.Draw(img, box, image.NewUniform(fade(color.RGBA{255, 255, 255, 255}, 0.85)), image.Point{}, draw.Over)
+	text := image.NewUniform(labelColor)
+	for i, entry := range opts.Legend {
+		top := box.Min.Y + pad + i*lineH
+		if entry.Color.A != 0 {
+			cx, cy := box.Min.X+pad+swatch/2, top+lineH/2
+			drawCircleAA(img, float64(cx), float64(cy), float64(swatch)/2, entry.Color)
+		}
+		r := masks[i].Rect.Add(image.Pt(box.Min.X+pad+swatch+pad, top))
+		draw.DrawMask(img, r, text, image.Point{}, masks[i], image.Point{}, draw.Over)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestPaletteAt(t *testing.T) {
+	p := palette{Colors: []color.RGBA{{0, 0, 0, 255}, {200, 100, 0, 255}, {200, 200, 200, 255}}}
+	tests := []struct {
+		t    float64
+		want color.RGBA
+	}{
+		{0, color.RGBA{0, 0, 0, 255}},
+		{0.25, color.RGBA{100, 50, 0, 255}},
+		{0.5, color.RGBA{200, 100, 0, 255}},
+		{1, color.RGBA{200, 200, 200, 255}},
+		{2, color.RGBA{200, 200, 200, 255}},
+	}
+	for _, test := range tests {
+		if got := p.at(test.t); got != test.want {
+			t.Errorf("at(%g) = %v, want %v", test.t, got, test.want)
+		}
+	}
+	if got := p.category(4, 5); got != p.Colors[1] {
+		t.Errorf("qualitative palettes should cycle, got %v", got)
+	}
+}
+
+func TestNodeMetrics(t *testing.T) {
+	// 0 -> 1 -> 2, 0 -> 2, and 3 -> 4 on their own
+	directed := Graph{{1, 2}, {2}, {}, {4}, {}}
+	if got, want := nodeDegrees(directed, true), []float64{2, 2, 2, 1, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("nodeDegrees = %v, want %v", got, want)
+	}
+	// Sugiyama levels count up from the sinks
+	if got, want := nodeLevels(directed, true), []float64{2, 1, 0, 1, 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("directed nodeLevels = %v, want %v", got, want)
+	}
+	undirected := Graph{{1}, {0, 2}, {1}, {4}, {3}}
+	if got, want := nodeLevels(undirected, false), []float64{0, 1, 2, 0, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("undirected nodeLevels = %v, want %v", got, want)
+	}
+	// The bigger component comes first
+	if got, want := nodeComponents(Graph{{}, {2}, {3}, {}, {}}), []int{1, 0, 0, 0, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("nodeComponents = %v, want %v", got, want)
+	}
+}
+
+func styleTestData(values ...string) (*GraphData, PosGraph) {
+	data := &GraphData{Graph: make(Graph, len(values))}
+	for _, v := range values {
+		attrs := Attrs{}
+		if v != "" {
+			attrs["group"] = v
+		}
+		data.Attrs.Nodes = append(data.Attrs.Nodes, attrs)
+	}
+	return data, make(PosGraph, len(values))
+}
+
+func TestApplyStyleCategories(t *testing.T) {
+	data, graph := styleTestData("b", "a", "", "b")
+	legend, err := applyStyle(graph, data, StyleOptions{ColorBy: "group"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tab10 := palettes["tab10"].Colors
+	want := []LegendEntry{{"a", tab10[0]}, {"b", tab10[1]}, {"(none)", missingColor}}
+	if !reflect.DeepEqual(legend, want) {
+		t.Errorf("legend = %v, want %v", legend, want)
+	}
+	colors := []color.RGBA{graph[0].Color, graph[1].Color, graph[2].Color, graph[3].Color}
+	if want := []color.RGBA{tab10[1], tab10[0], missingColor, tab10[1]}; !reflect.DeepEqual(colors, want) {
+		t.Errorf("colors = %v, want %v", colors, want)
+	}
+
+	if _, err := applyStyle(graph, data, StyleOptions{ColorBy: "missing"}); err == nil {
+		t.Errorf("coloring by an attribute no node has should fail")
+	}
+	if _, err := applyStyle(graph, data, StyleOptions{SizeBy: "group"}); err == nil {
+		t.Errorf("sizing by a non-numeric attribute should fail")
+	}
+}
+
+func TestApplyStyleNumbers(t *testing.T) {
+	values := make([]string, 21)
+	for i := range values {
+		values[i] = strconv.Itoa(i * 5)
+	}
+	data, graph := styleTestData(values...)
+	legend, err := applyStyle(graph, data, StyleOptions{ColorBy: "group", SizeBy: "group", Palette: "plasma"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plasma := palettes["plasma"]
+	if graph[0].Color != plasma.at(0) || graph[20].Color != plasma.at(1) || graph[10].Color != plasma.at(0.5) {
+		t.Errorf("colors not spread along the palette: %v %v %v", graph[0].Color, graph[10].Color, graph[20].Color)
+	}
+	if len(legend) != 5 || legend[0].Label != "0" || legend[4].Label != "100" {
+		t.Errorf("legend = %v, want 5 stops from 0 to 100", legend)
+	}
+	if graph[0].Size != minNodeScale || graph[20].Size != maxNodeScale {
+		t.Errorf("sizes run from %g to %g, want %g to %g", graph[0].Size, graph[20].Size, minNodeScale, maxNodeScale)
+	}
+}
+
+// Whole numbers past 2^53, where x++ no longer changes x, still get a legend entry per value
+func TestColorByHugeNumbers(t *testing.T) {
+	nums := []float64{1 << 53, 1<<53 + 2}
+	legend := colorByNumber(make(PosGraph, 2), nums, palettes["viridis"])
+	if len(legend) != 3 || legend[2].Label != "9.007199254740994e+15" {
+		t.Errorf("legend = %v, want 3 entries up to 2^53+2", legend)
+	}
+}
+
+func TestDrawLegend(t *testing.T) {
+	opts := defaultRenderOptions
+	opts.Width, opts.Height = 400, 400
+	opts.Legend = []LegendEntry{{"first", color.RGBA{255, 0, 0, 255}}, {"second", color.RGBA{0, 255, 0, 255}}}
+	img := renderImage(testgraph, false, opts)
+	red := 0
+	for y := 0; y < 100; y++ {
+		for x := 300; x < 400; x++ {
+			if img.RGBAAt(x, y) == (color.RGBA{255, 0, 0, 255}) {
+				red++
+			}
+		}
+	}
+	if red == 0 {
+		t.Errorf("no legend swatch in the top right corner")
+	}
+	opts.Legend = nil
+	plain := renderImage(testgraph, false, opts)
+	corner := image.Rect(300, 0, 400, 100)
+	if darkPixels(img, corner) <= darkPixels(plain, corner) {
+		t.Errorf("no legend text in the top right corner")
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strings"
+)
+
+/***** SVG output *****/
+
+// Hex form of a color for SVG attributes
+func svgColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// Turns a node name into something usable in an XML id: letters, digits, '-' and '.' are
+// kept and any other byte is written as _xHH_. The ids all start with "node-", so a leading
+// digit is fine.
+func svgID(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '.' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "_x%02X_", c)
+		}
+	}
+	return b.String()
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// Writes the graph as an SVG drawing, sized, laid out and scaled like the PNG from drawGraph:
+// edges first, then nodes on top. Directed edges end in an arrowhead at the edge of the target
+// node, drawn as a marker shaped like drawDirectedLine's. Each node is a circle with id
+// "node-<name>" and a title holding its name; edges carry the names of their endpoints in
+// data-source and data-target, so the drawing can be styled with CSS.
+func writeSVG(w io.Writer, graph PosGraph, directed bool, opts RenderOptions) error {
+	out := bufio.NewWriter(w)
+	boundary := getBoundary(graph)
+	width, height := opts.imageSize(graph)
+	inset := opts.inset(graph)
+
+	fmt.Fprintf(out, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprintf(out, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n",
+		width, height, width, height)
+	if directed {
+		// Two 20px strokes 30 degrees either side of the edge, like drawDirectedLine
+		fmt.Fprintf(out, "<defs>\n")
+		fmt.Fprintf(out, "  <marker id=\"arrow\" markerUnits=\"userSpaceOnUse\" markerWidth=\"%g\" markerHeight=\"%g\" viewBox=\"0 -1 22 22\" refX=\"20\" refY=\"10\" orient=\"auto\">\n",
+			22*opts.Scale, 22*opts.Scale)
+		fmt.Fprintf(out, "    <path d=\"M 2.68 0 L 20 10 L 2.68 20\" fill=\"none\" stroke=\"%s\"/>\n", svgColor(arrowColor))
+		fmt.Fprintf(out, "  </marker>\n")
+		fmt.Fprintf(out, "</defs>\n")
+	}
+	if bg := opts.Background; bg.A != 0 {
+		// un-premultiply
+		nrgba := color.NRGBAModel.Convert(bg).(color.NRGBA)
+		fmt.Fprintf(out, "<rect width=\"100%%\" height=\"100%%\" fill=\"%s\" fill-opacity=\"%.3g\"/>\n",
+			svgColor(color.RGBA{nrgba.R, nrgba.G, nrgba.B, 255}), float64(nrgba.A)/255)
+	}
+
+	fmt.Fprintf(out, "<g id=\"edges\" stroke=\"%s\" stroke-width=\"%g\"", svgColor(edgeColor), opts.EdgeWidth*opts.Scale)
+	if opts.EdgeOpacity < 1 {
+		fmt.Fprintf(out, " stroke-opacity=\"%g\"", opts.EdgeOpacity)
+	}
+	if directed {
+		fmt.Fprintf(out, " marker-end=\"url(#arrow)\"")
+	}
+	fmt.Fprintf(out, ">\n")
+	forEachEdge(graph, directed, func(u, v, j int) {
+		path := graph.edgePath(u, j)
+		points := make([][2]float64, len(path))
+		for k, p := range path {
+			x, y := translateCoordsInset(float32(p.X), float32(p.Y), boundary, width, height, inset)
+			points[k] = [2]float64{float64(x), float64(y)}
+		}
+		last := len(points) - 1
+		if len(points) == 2 && points[0] == points[1] {
+			// self-loops and overlapping nodes: nothing to draw
+			return
+		}
+		if directed {
+			// stop at the edge of the target node, where the arrow tip goes
+			points[last][0], points[last][1] = shortenLine(points[last-1][0], points[last-1][1], points[last][0], points[last][1], float64(opts.radiusOf(graph[v])))
+		}
+		ends := fmt.Sprintf("data-source=\"%s\" data-target=\"%s\"", xmlEscape(graph[u].Name), xmlEscape(graph[v].Name))
+		if len(points) == 2 {
+			fmt.Fprintf(out, "  <line class=\"edge\" %s x1=\"%g\" y1=\"%g\" x2=\"%.2f\" y2=\"%.2f\"/>\n",
+				ends, points[0][0], points[0][1], points[1][0], points[1][1])
+			return
+		}
+		// Bundled edges are polylines through their bends
+		fmt.Fprintf(out, "  <polyline class=\"edge\" %s fill=\"none\" points=\"", ends)
+		for k, p := range points {
+			if k > 0 {
+				fmt.Fprintf(out, " ")
+			}
+			fmt.Fprintf(out, "%.2f,%.2f", p[0], p[1])
+		}
+		fmt.Fprintf(out, "\"/>\n")
+	})
+	fmt.Fprintf(out, "</g>\n")
+
+	fmt.Fprintf(out, "<g id=\"nodes\" fill=\"%s\">\n", svgColor(nodeColor))
+	for _, node := range graph {
+		x, y := translateCoordsInset(node.X, node.Y, boundary, width, height, inset)
+		fill := ""
+		if node.fill() != nodeColor {
+			fill = fmt.Sprintf(" fill=\"%s\"", svgColor(node.fill()))
+		}
+		fmt.Fprintf(out, "  <circle class=\"node\" id=\"node-%s\" cx=\"%d\" cy=\"%d\" r=\"%d\"%s><title>%s</title></circle>\n",
+			svgID(node.Name), x, y, opts.radiusOf(node), fill, xmlEscape(node.Name))
+	}
+	fmt.Fprintf(out, "</g>\n")
+	fmt.Fprintf(out, "</svg>\n")
+	return out.Flush()
+}
+
+// Moves (x2, y2) a distance d back towards (x1, y1)
+func shortenLine(x1, y1, x2, y2, d float64) (float64, float64) {
+	dx, dy := x2-x1, y2-y1
+	r := math.Sqrt(dx*dx + dy*dy)
+	if r <= d {
+		return x2, y2
+	}
+	return x2 - d*dx/r, y2 - d*dy/r
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image/color"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestWriteSVG(t *testing.T) {
+	graph := PosGraph{
+		{X: 0, Y: 0, Edges: []int{1}, Name: "a b"},
+		{X: 1, Y: 1, Edges: []int{0}, Name: "<c>"},
+	}
+	var buf bytes.Buffer
+	if err := writeSVG(&buf, graph, true, RenderOptions{Width: 200, Height: 100, Background: color.RGBA{255, 255, 255, 255}, Scale: 1}); err != nil {
+		t.Fatal(err)
+	}
+	svg := buf.String()
+
+	// well-formed XML
+	dec := xml.NewDecoder(strings.NewReader(svg))
+	for {
+		_, err := dec.Token()
+		if err != nil {
+			if err != io.EOF {
+				t.Fatalf("invalid SVG: %v\n%s", err, svg)
+			}
+			break
+		}
+	}
+	for _, want := range []string{
+		`id="node-a_x20_b"`, `id="node-_x3C_c_x3E_"`, `<title>&lt;c&gt;</title>`,
+		`marker-end="url(#arrow)"`, `data-source="a b" data-target="&lt;c&gt;"`,
+	} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("SVG is missing %s:\n%s", want, svg)
+		}
+	}
+	if n := strings.Count(svg, `class="edge"`); n != 2 {
+		t.Errorf("directed graph drew %d edges, want 2", n)
+	}
+
+	buf.Reset()
+	if err := writeSVG(&buf, graph, false, RenderOptions{Width: 200, Height: 100, Scale: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(buf.String(), `class="edge"`); n != 1 {
+		t.Errorf("undirected graph drew %d edges, want 1", n)
+	}
+	if strings.Contains(buf.String(), "marker") {
+		t.Errorf("undirected graph has arrowheads")
+	}
+}
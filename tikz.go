@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+)
+
+/***** TikZ output *****/
+
+type TikZOptions struct {
+	// Width of the picture in points; the height follows from the layout's aspect ratio
+	Width float64
+	// Put node names under the nodes
+	Labels bool
+}
+
+var defaultTikZOptions = TikZOptions{Width: 12 * 72 / 2.54, Labels: true}
+
+// Escapes the characters LaTeX treats specially, so node names print as they are
+func latexEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString(`\textbackslash{}`)
+		case '~':
+			b.WriteString(`\textasciitilde{}`)
+		case '^':
+			b.WriteString(`\textasciicircum{}`)
+		case '#', '$', '%', '&', '_', '{', '}':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func tikzColor(name string, c color.RGBA) string {
+	return fmt.Sprintf("\\definecolor{%s}{RGB}{%d,%d,%d}\n", name, c.R, c.G, c.B)
+}
+
+// Writes the graph as a standalone LaTeX document holding one tikzpicture, which can be
+// compiled on its own or pulled into a paper with \includestandalone. Coordinates are in cm,
+// scaled so the picture is opts.Width wide, with y pointing up as in the layout. Nodes are
+// named n0, n1, ... in node order; directed edges are drawn with ->. Node and edge sizes keep
+// the proportions RenderPNG uses.
+func writeTikZ(w io.Writer, graph PosGraph, directed bool, opts TikZOptions) error {
+	out := bufio.NewWriter(w)
+	boundary := getBoundary(graph)
+	const ptPerCM = 72 / 2.54
+	scale := opts.Width / ptPerCM / float64(boundary.Right-boundary.Left)
+	// RenderPNG draws nodes of radius nodeRadius, 24 pixels across, on a 2000 pixel canvas
+	nodeSize := 2 * float64(nodeRadius) / 2000 * opts.Width
+
+	fmt.Fprintf(out, "\\documentclass[tikz,border=2pt]{standalone}\n")
+	fmt.Fprintf(out, "\\usetikzlibrary{arrows.meta}\n")
+	fmt.Fprintf(out, "%s%s%s", tikzColor("edgecolor", edgeColor), tikzColor("arrowcolor", arrowColor), tikzColor("nodecolor", nodeColor))
+	fmt.Fprintf(out, "\\begin{document}\n")
+	fmt.Fprintf(out, "\\begin{tikzpicture}[\n")
+	fmt.Fprintf(out, "  vertex/.style={circle, fill=nodecolor, inner sep=0pt, minimum size=%.2fpt},\n", nodeSize)
+	fmt.Fprintf(out, "  every label/.style={font=\\tiny},\n")
+	fmt.Fprintf(out, "  graph edge/.style={draw=edgecolor, line width=%.2fpt},\n", opts.Width/2000)
+	fmt.Fprintf(out, "  graph arc/.style={graph edge, ->, >={Stealth[color=arrowcolor]}},\n")
+	fmt.Fprintf(out, "]\n")
+
+	for i, node := range graph {
+		x := float64(node.X-boundary.Left) * scale
+		y := float64(node.Y-boundary.Bottom) * scale
+		style := ""
+		if fill := node.fill(); fill != nodeColor {
+			style += fmt.Sprintf(", fill={rgb,255:red,%d;green,%d;blue,%d}", fill.R, fill.G, fill.B)
+		}
+		if node.sizeFactor() != 1 {
+			style += fmt.Sprintf(", minimum size=%.2fpt", nodeSize*node.sizeFactor())
+		}
+		if opts.Labels {
+			style += fmt.Sprintf(", label=below:{%s}", latexEscape(node.Name))
+		}
+		fmt.Fprintf(out, "\\node[vertex%s] (n%d) at (%.3f, %.3f) {};\n", style, i, x, y)
+	}
+	style := "graph edge"
+	if directed {
+		style = "graph arc"
+	}
+	forEachEdge(graph, directed, func(u, v, j int) {
+		if u == v {
+			fmt.Fprintf(out, "\\path[%s] (n%d) edge[loop above] (n%d);\n", style, u, v)
+		} else if j < len(graph[u].Bends) && graph[u].Bends[j] != nil {
+			// Bundled edges go through their bends
+			fmt.Fprintf(out, "\\draw[%s] (n%d)", style, u)
+			for _, b := range graph[u].Bends[j] {
+				fmt.Fprintf(out, " -- (%.3f, %.3f)", (b.X-float64(boundary.Left))*scale, (b.Y-float64(boundary.Bottom))*scale)
+			}
+			fmt.Fprintf(out, " -- (n%d);\n", v)
+		} else {
+			fmt.Fprintf(out, "\\path[%s] (n%d) edge (n%d);\n", style, u, v)
+		}
+	})
+
+	fmt.Fprintf(out, "\\end{tikzpicture}\n")
+	fmt.Fprintf(out, "\\end{document}\n")
+	return out.Flush()
+}
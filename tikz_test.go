@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLatexEscape(t *testing.T) {
+	if got, want := latexEscape(`a_b {50%} \x`), `a\_b \{50\%\} \textbackslash{}x`; got != want {
+		t.Errorf("latexEscape = %s, want %s", got, want)
+	}
+}
+
+func TestWriteTikZ(t *testing.T) {
+	graph := PosGraph{
+		{X: -1, Y: 0, Edges: []int{1}, Name: "a_1"},
+		{X: 1, Y: 2, Edges: []int{0, 1, 1}, Name: "b"},
+	}
+	var buf bytes.Buffer
+	opts := TikZOptions{Width: 72 / 2.54 * 10, Labels: true}
+	if err := writeTikZ(&buf, graph, false, opts); err != nil {
+		t.Fatal(err)
+	}
+	tex := buf.String()
+	for _, want := range []string{
+		`\node[vertex, label=below:{a\_1}] (n0) at (0.000, 0.000) {};`,
+		`\node[vertex, label=below:{b}] (n1) at (10.000, 10.000) {};`,
+		`\path[graph edge] (n0) edge (n1);`,
+		`\path[graph edge] (n1) edge[loop above] (n1);`,
+	} {
+		if !strings.Contains(tex, want) {
+			t.Errorf("TikZ output is missing %s:\n%s", want, tex)
+		}
+	}
+	if n := strings.Count(tex, `\path`); n != 2 {
+		t.Errorf("undirected graph drew %d edges, want 2", n)
+	}
+
+	buf.Reset()
+	if err := writeTikZ(&buf, PosGraph{{Name: "c"}}, true, TikZOptions{Width: 100}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "label=") {
+		t.Errorf("labels written with Labels off")
+	}
+}